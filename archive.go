@@ -0,0 +1,140 @@
+package dpk
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//=============================================================================
+// Archive access
+
+// Archive gives read access to the content of a data export, whether it has
+// been unzipped in a directory or is still a ZIP file.
+type Archive struct {
+	fs.FS
+	zip *zip.ReadCloser
+}
+
+// OpenArchive opens the archive stored at archivePath, which can be a directory
+// or a .zip file. Archive must be closed after use.
+func OpenArchive(archivePath string) (*Archive, error) {
+	info, err := os.Stat(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return &Archive{FS: os.DirFS(archivePath)}, nil
+	}
+
+	if !strings.EqualFold(filepath.Ext(archivePath), ".zip") {
+		return nil, fmt.Errorf("unsupported archive format: %s", archivePath)
+	}
+	r, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	return &Archive{FS: r, zip: r}, nil
+}
+
+// Close releases the resources used to read the archive.
+func (a *Archive) Close() error {
+	if a.zip != nil {
+		return a.zip.Close()
+	}
+	return nil
+}
+
+//=============================================================================
+// Twitter archive data files
+//
+// Each data file in a Twitter archive is a Javascript file assigning a JSON
+// array to a global variable, for example:
+//
+//    window.YTD.tweet.part0 = [ ... ]
+//
+// Large archives split a data type over several files: tweet.js, tweet-part1.js,
+// tweet-part2.js, etc. Each of them is assigned to the matching partN variable.
+
+// archiveHeader matches the Javascript assignment at the beginning of archive data files.
+var archiveHeader = regexp.MustCompile(`^\s*window\.YTD\.[A-Za-z0-9_]+\.part([0-9]+)\s*=\s*`)
+
+// archivePart is a data file holding one part of a given data type.
+type archivePart struct {
+	filename string
+	part     int
+}
+
+// ReadArchiveData reads all the files for the data type name (for example "tweet")
+// in directory dir of the archive and returns the merged list of JSON elements
+// they contain, in part order.
+func ReadArchiveData(archive fs.FS, dir, name string) ([]json.RawMessage, error) {
+	parts, err := findArchiveParts(archive, dir, name)
+	if err != nil {
+		return nil, err
+	}
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("cannot find %s data in %s: %w", name, dir, fs.ErrNotExist)
+	}
+
+	var elements []json.RawMessage
+	for _, p := range parts {
+		data, err := fs.ReadFile(archive, path.Join(dir, p.filename))
+		if err != nil {
+			return nil, err
+		}
+		var partElements []json.RawMessage
+		if err = json.Unmarshal(stripArchiveHeader(data), &partElements); err != nil {
+			return nil, fmt.Errorf("cannot parse %s: %w", p.filename, err)
+		}
+		elements = append(elements, partElements...)
+	}
+	return elements, nil
+}
+
+// findArchiveParts returns the list of files in directory dir of the archive
+// containing data for the data type name, sorted by part number.
+func findArchiveParts(archive fs.FS, dir, name string) ([]archivePart, error) {
+	files, err := fs.ReadDir(archive, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	partFile := regexp.MustCompile(`^` + regexp.QuoteMeta(name) + `(-part([0-9]+))?\.js$`)
+	var parts []archivePart
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+		matches := partFile.FindStringSubmatch(f.Name())
+		if matches == nil {
+			continue
+		}
+		part := 0
+		if matches[2] != "" {
+			part, _ = strconv.Atoi(matches[2])
+		}
+		parts = append(parts, archivePart{filename: f.Name(), part: part})
+	}
+
+	sort.Slice(parts, func(i, j int) bool { return parts[i].part < parts[j].part })
+	return parts, nil
+}
+
+// stripArchiveHeader removes the Javascript variable assignment in front of the JSON data.
+func stripArchiveHeader(data []byte) []byte {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")) // UTF-8 BOM
+	if loc := archiveHeader.FindIndex(data); loc != nil {
+		return data[loc[1]:]
+	}
+	return data
+}
@@ -0,0 +1,130 @@
+package dpk_test
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/processone/dpk"
+)
+
+func TestReadArchiveDataParts(t *testing.T) {
+	archiveDir := "fixtures/twitter-2018"
+	data, err := dpk.ReadArchiveData(os.DirFS(archiveDir), ".", "tweet")
+	if err != nil {
+		t.Errorf("Cannot read archive data from '%s': %s", archiveDir, err)
+		return
+	}
+
+	// tweet.js contains two tweets and tweet-part1.js four more
+	expected := []string{"1078253946104922112", "1078253946104922113", "817366093016002560",
+		"699182563473170432", "699545000000000000", "699907000000000000"}
+	if len(data) != len(expected) {
+		t.Errorf("Incorrect number of tweets. Got: %d Expected: %d", len(data), len(expected))
+		return
+	}
+	for i, element := range data {
+		var tweet dpk.Tweet
+		if err = json.Unmarshal(element, &tweet); err != nil {
+			t.Errorf("Cannot parse tweet %d: %s", i, err)
+			continue
+		}
+		if tweet.Id != expected[i] {
+			t.Errorf("Incorrect tweet order. Got: '%s' Expected: '%s'", tweet.Id, expected[i])
+		}
+	}
+}
+
+func TestReadArchiveDataMissing(t *testing.T) {
+	if _, err := dpk.ReadArchiveData(os.DirFS("fixtures/twitter-2018"), ".", "like"); err == nil {
+		t.Errorf("Reading missing data type should fail")
+	}
+}
+
+func TestTwitterToMDZip(t *testing.T) {
+	// Twitter archive ZIP files contain the archive files at the root, but
+	// archives that have been compressed again after being unzipped may have
+	// them in a top-level directory.
+	for _, prefix := range []string{"", "twitter-2022-11-02/"} {
+		zipFile := filepath.Join(t.TempDir(), "archive.zip")
+		if err := zipDir("fixtures/twitter-2022", prefix, zipFile); err != nil {
+			t.Errorf("Cannot create ZIP file: %s", err)
+			return
+		}
+
+		outputDir := t.TempDir()
+		if err := dpk.TwitterToMD(zipFile, outputDir, dpk.TwitterOptions{}); err != nil {
+			t.Errorf("Cannot convert ZIP archive with prefix '%s': %s", prefix, err)
+			continue
+		}
+		media := filepath.Join(outputDir, "2022", "10", "31", "002", "1587200000000000000-FgXyZ12WAAEaBcD.jpg")
+		if _, err := os.Stat(media); err != nil {
+			t.Errorf("Media file was not copied from ZIP archive with prefix '%s': %s", prefix, err)
+		}
+	}
+}
+
+func TestTwitterArchiveToMDInMemory(t *testing.T) {
+	archive := fstest.MapFS{
+		"tweet.js": &fstest.MapFile{Data: []byte(`window.YTD.tweet.part0 = [ {
+  "id_str" : "1",
+  "created_at" : "Mon Jan 07 10:00:00 +0000 2019",
+  "full_text" : "Hello from memory",
+  "lang" : "en"
+} ]`)},
+	}
+	outputDir := t.TempDir()
+	if err := dpk.TwitterArchiveToMD(archive, outputDir, dpk.TwitterOptions{}); err != nil {
+		t.Errorf("Cannot convert in-memory archive: %s", err)
+		return
+	}
+	post, err := ioutil.ReadFile(filepath.Join(outputDir, "2019", "01", "07", "001", "post.md"))
+	if err != nil {
+		t.Errorf("Cannot read converted post: %s", err)
+		return
+	}
+	if string(post) != "Hello from memory" {
+		t.Errorf("Incorrect post content. Got: '%s' Expected: 'Hello from memory'", post)
+	}
+}
+
+// zipDir creates a ZIP file from the content of directory dir, storing all
+// files under the given path prefix.
+func zipDir(dir, prefix, zipFile string) error {
+	out, err := os.Create(zipFile)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	w := zip.NewWriter(out)
+	err = filepath.Walk(dir, func(name string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, name)
+		if err != nil {
+			return err
+		}
+		f, err := w.Create(prefix + filepath.ToSlash(rel))
+		if err != nil {
+			return err
+		}
+		in, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		_, err = io.Copy(f, in)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return w.Close()
+}
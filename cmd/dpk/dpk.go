@@ -0,0 +1,115 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/processone/dpk"
+)
+
+// dpk is a tool to work on the output directory of converted archives.
+//
+// - `index`: rebuild the SQLite and full-text search indexes of all posts from
+//   their post.md and metadata.json files.
+//
+// Usage:
+//    dpk index [OutputDir]
+//
+// - `search`: search posts, with terms and "quoted phrases".
+//
+// Usage:
+//    dpk search [options] [OutputDir] [Query]
+
+func main() {
+	args := os.Args[1:]
+
+	if len(args) < 2 {
+		fmt.Println("Missing command or directory")
+		usage()
+		os.Exit(1)
+	}
+
+	command := args[0]
+	switch command {
+	case "index":
+		if err := dpk.BuildIndex(args[1]); err != nil {
+			fmt.Println("Cannot build index:", err)
+			os.Exit(1)
+		}
+		if err := dpk.BuildSearchIndex(args[1]); err != nil {
+			fmt.Println("Cannot build search index:", err)
+			os.Exit(1)
+		}
+	case "search":
+		if err := search(args[1:]); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	default:
+		fmt.Println("Unknown command:", command)
+		usage()
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Println("Usage:")
+	fmt.Println("- Rebuild the SQLite index, dpk.sqlite, and the search index of posts in output directory")
+	fmt.Println("  dpk index [OutputDir]")
+	fmt.Println("")
+	fmt.Println("- Search posts, with terms and \"quoted phrases\"")
+	fmt.Println("  dpk search [-from YYYY-MM-DD] [-to YYYY-MM-DD] [-tag Tag] [-limit N] [OutputDir] [Query]")
+}
+
+//=============================================================================
+// Search command
+
+func search(args []string) error {
+	flags := flag.NewFlagSet("search", flag.ExitOnError)
+	from := flags.String("from", "", "Only return posts created on or after this date (YYYY-MM-DD)")
+	to := flags.String("to", "", "Only return posts created on or before this date (YYYY-MM-DD)")
+	tags := flags.String("tag", "", "Only return posts with these tags, separated by commas")
+	limit := flags.Int("limit", 20, "Maximum number of results, 0 for no limit")
+	flags.Usage = usage
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() < 2 {
+		usage()
+		return fmt.Errorf("missing directory or query")
+	}
+
+	options := dpk.SearchOptions{Limit: *limit}
+	if *from != "" {
+		date, err := time.Parse("2006-01-02", *from)
+		if err != nil {
+			return fmt.Errorf("invalid from date: %w", err)
+		}
+		options.From = date
+	}
+	if *to != "" {
+		date, err := time.Parse("2006-01-02", *to)
+		if err != nil {
+			return fmt.Errorf("invalid to date: %w", err)
+		}
+		// Include posts of the last day
+		options.To = date.AddDate(0, 0, 1)
+	}
+	if *tags != "" {
+		options.Tags = strings.Split(*tags, ",")
+	}
+
+	outputDir := flags.Arg(0)
+	query := strings.Join(flags.Args()[1:], " ")
+	results, err := dpk.Search(outputDir, query, options)
+	if err != nil {
+		return err
+	}
+	for _, r := range results {
+		fmt.Printf("%s (%s)\n  %s\n", r.Path, r.CreatedAt.Format("2 Jan 2006"), r.Snippet)
+	}
+	return nil
+}
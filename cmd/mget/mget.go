@@ -2,8 +2,11 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/processone/dpk/pkg/semweb"
 )
@@ -22,6 +25,12 @@ import (
 //
 // Usage:
 //    mget profiles [URL]
+//
+// - `trace`: mget can print the redirect chain of a URL, with the status code,
+//   Location header and response time of each hop, to debug link resolution:
+//
+// Usage:
+//    mget trace [-json] [URL]
 
 func main() {
 	args := os.Args[1:]
@@ -32,21 +41,31 @@ func main() {
 		os.Exit(1)
 	}
 
-	if len(args) == 1 {
+	command := args[0]
+	switch {
+	case command == "profiles":
+		if len(args) < 2 {
+			fmt.Println("Missing url")
+			usage()
+			os.Exit(1)
+		}
+		err := getProfiles(args[1])
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	case command == "trace":
+		if err := trace(args[1:]); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	case len(args) == 1:
 		// Retrieve page and extract metadata
 		getPageMetadata(args[0])
-	}
-
-	if len(args) >= 2 {
-		command := args[0]
-		switch command {
-		case "profiles":
-			err := getProfiles(args[1])
-			if err != nil {
-				fmt.Println(err)
-				os.Exit(1)
-			}
-		}
+	default:
+		fmt.Println("Unknown command:", command)
+		usage()
+		os.Exit(1)
 	}
 }
 
@@ -57,6 +76,9 @@ func usage() {
 	fmt.Println("")
 	fmt.Println("- Crawl pages from starting point to gather list of user profiles")
 	fmt.Println("Usage: mget profiles [URL]")
+	fmt.Println("")
+	fmt.Println("- Print the redirect chain of a URL, as text or JSON")
+	fmt.Println("Usage: mget trace [-json] [URL]")
 }
 
 //=============================================================================
@@ -125,6 +147,104 @@ func getProfiles(profileURL string) error {
 	return nil
 }
 
+//=============================================================================
+// Redirect trace command
+
+// traceReport is the JSON output of the trace command.
+type traceReport struct {
+	Url      string
+	Hops     []traceHop
+	FinalUrl string `json:",omitempty"`
+	Status   int    `json:",omitempty"`
+	Title    string `json:",omitempty"`
+	// Loop is the URL visited twice, when a redirect loop is detected.
+	Loop  string `json:",omitempty"`
+	Error string `json:",omitempty"`
+}
+
+type traceHop struct {
+	Url        string
+	Status     int
+	Location   string `json:",omitempty"`
+	DurationMs int64
+	duration   time.Duration
+}
+
+func trace(args []string) error {
+	flags := flag.NewFlagSet("trace", flag.ExitOnError)
+	asJSON := flags.Bool("json", false, "Print the redirect chain as JSON")
+	flags.Usage = usage
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() < 1 {
+		usage()
+		return fmt.Errorf("missing url")
+	}
+
+	link := flags.Arg(0)
+	client := semweb.NewClient()
+	res, err := client.Resolve(link)
+	report := traceReport{Url: link}
+	for _, hop := range res.Chain {
+		report.Hops = append(report.Hops, traceHop{
+			Url:        hop.Url,
+			Status:     hop.Status,
+			Location:   hop.Location,
+			DurationMs: hop.Duration.Milliseconds(),
+			duration:   hop.Duration,
+		})
+	}
+	switch {
+	case errors.Is(err, semweb.ErrRedirectLoop):
+		last := res.Chain[len(res.Chain)-1]
+		report.Loop, _ = semweb.RedirectUrl(last.Url, last.Location)
+	case err != nil:
+		report.Error = err.Error()
+	default:
+		report.FinalUrl = res.FinalUrl
+		report.Status = res.Status
+		report.Title = res.Page.Title()
+	}
+
+	if *asJSON {
+		data, err := json.MarshalIndent(report, "", "\t")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	} else {
+		printTrace(report)
+	}
+	if err != nil {
+		return fmt.Errorf("cannot resolve %s", link)
+	}
+	return nil
+}
+
+// printTrace prints the redirect chain as text.
+func printTrace(report traceReport) {
+	var total time.Duration
+	for i, hop := range report.Hops {
+		total += hop.duration
+		fmt.Printf("%d. %d %s (%s)\n", i+1, hop.Status, hop.Url, hop.duration.Round(100*time.Microsecond))
+		if hop.Location != "" {
+			fmt.Printf("   Location: %s\n", hop.Location)
+		}
+	}
+	switch {
+	case report.Loop != "":
+		fmt.Println("Redirect loop detected:", report.Loop, "was already visited")
+	case report.Error != "":
+		fmt.Println("Error:", report.Error)
+	default:
+		fmt.Printf("Final URL: %s (%d redirect(s), %s)\n", report.FinalUrl, len(report.Hops)-1, total.Round(100*time.Microsecond))
+		if report.Title != "" {
+			fmt.Println("Title:", report.Title)
+		}
+	}
+}
+
 /*
 TODO:
 
@@ -1,15 +1,52 @@
 package main
 
 import (
+	"flag"
 	"fmt"
-	"github.com/processone/dpk"
 	"os"
+	"strings"
+	"time"
+
+	"github.com/processone/dpk"
+	"github.com/processone/dpk/pkg/semweb"
 )
 
 // This tool is used to convert data from your Twitter archive to a set of Markdown files.
 // You can request your data from Twitter at this URL: https://twitter.com/settings/your_twitter_data
 func main() {
-	args := os.Args[1:]
+	var options dpk.TwitterOptions
+	flag.StringVar(&options.ScreenName, "screen-name", "",
+		"Twitter handle of the archive owner (defaults to the one found in archive)")
+	replies := flag.String("replies", "skip",
+		"How to convert replies to other users: skip, keep (along other posts) or separate (in replies/ directory)")
+	format := flag.String("format", "dpk",
+		"Output format: "+strings.Join(dpk.Exporters(), ", "))
+	frontMatter := flag.String("front-matter", "none",
+		"Front matter added to posts in dpk and hugo formats: none, yaml or toml (jekyll format always uses yaml)")
+	incremental := flag.Bool("incremental", false,
+		"Only write new and changed posts, in directories named after their ID (dpk format)")
+	defaultCache, _ := semweb.DefaultCachePath()
+	cachePath := flag.String("cache", defaultCache,
+		"File storing the resolution of links across runs, empty to disable")
+	cacheTTL := flag.Duration("cache-ttl", 30*24*time.Hour,
+		"Duration after which cached links are resolved again")
+	offline := flag.Bool("offline", false,
+		"Do not access the network, only use cached link resolutions")
+	rewriteRules := flag.String("rewrite-rules", "",
+		"YAML or JSON file of rules rewriting links, applied before the default rules")
+	discoverEmbeds := flag.Bool("discover-embeds", false,
+		"Retrieve linked pages to find their oEmbed representation, for sites not known to be embeddable")
+	embedPlayers := flag.Bool("embed-players", false,
+		"Keep the players of embedded content, such as Vimeo videos, which load third-party content when posts are displayed")
+	previewCards := flag.Bool("preview-cards", false,
+		"Render links as preview cards, with the title, description and image of the linked pages")
+	youtubeFacade := flag.Bool("youtube-facade", false,
+		"Replace YouTube video thumbnails with the video player, from youtube-nocookie.com, when clicked")
+	canonical := flag.Bool("canonical", false,
+		"Replace short URLs with the canonical URL of their target page, when declared")
+	flag.Usage = usage
+	flag.Parse()
+	args := flag.Args()
 
 	if len(args) < 2 {
 		fmt.Println("Missing argument.")
@@ -17,11 +54,95 @@ func main() {
 		os.Exit(1)
 	}
 
-	if err := dpk.TwitterToMD(args[0], args[1]); err != nil {
+	switch *replies {
+	case "skip":
+		options.Replies = dpk.SkipReplies
+	case "keep":
+		options.Replies = dpk.KeepReplies
+	case "separate":
+		options.Replies = dpk.SeparateReplies
+	default:
+		fmt.Println("Unknown replies policy:", *replies)
+		usage()
+		os.Exit(1)
+	}
+
+	exporter, err := dpk.GetExporter(*format)
+	if err != nil {
+		fmt.Println(err)
+		usage()
+		os.Exit(1)
+	}
+	fm, err := dpk.ParseFrontMatter(*frontMatter)
+	if err != nil {
+		fmt.Println(err)
+		usage()
+		os.Exit(1)
+	}
+	if _, ok := exporter.(dpk.DPKExporter); *incremental && !ok {
+		fmt.Println("Incremental mode is not supported by format:", *format)
+		usage()
+		os.Exit(1)
+	}
+	switch e := exporter.(type) {
+	case dpk.DPKExporter:
+		e.FrontMatter = fm
+		e.Incremental = *incremental
+		exporter = e
+	case dpk.HugoExporter:
+		e.FrontMatter = fm
+		exporter = e
+	case dpk.JekyllExporter:
+		// Jekyll posts always have YAML front matter
+		if fm == dpk.TOMLFrontMatter {
+			fmt.Println("TOML front matter is not supported by format:", *format)
+			usage()
+			os.Exit(1)
+		}
+	default:
+		if fm != dpk.NoFrontMatter {
+			fmt.Println("Front matter is not supported by format:", *format)
+			usage()
+			os.Exit(1)
+		}
+	}
+	options.Exporter = exporter
+	options.CanonicalLinks = *canonical
+	options.YouTubeFacade = *youtubeFacade
+	options.DiscoverEmbeds = *discoverEmbeds
+	options.EmbedPlayers = *embedPlayers
+	options.PreviewCards = *previewCards
+	if *rewriteRules != "" {
+		if options.Rewriter, err = semweb.LoadRewriter(*rewriteRules); err != nil {
+			fmt.Println("Cannot load rewrite rules:", err)
+			os.Exit(1)
+		}
+	}
+
+	if *cachePath != "" {
+		cache, err := semweb.OpenCache(*cachePath, *cacheTTL)
+		if err != nil {
+			fmt.Println("Cannot open link cache:", err)
+			os.Exit(1)
+		}
+		cache.Offline = *offline
+		options.LinkCache = cache
+	} else if *offline {
+		// Offline mode without cached results: links are not resolved
+		options.LinkCache = &semweb.Cache{Offline: true}
+	}
+
+	if err := dpk.TwitterToMD(args[0], args[1], options); err != nil {
 		fmt.Println(err)
 	}
+	if err := options.LinkCache.Save(); err != nil {
+		fmt.Println("Cannot save link cache:", err)
+	}
 }
 
 func usage() {
-	fmt.Println("Usage: twitter-to-md [TwitterArchiveDir] [OutputDir]")
+	fmt.Println("Usage: twitter-to-md [options] [TwitterArchiveDir|TwitterArchive.zip] [OutputDir]")
+	fmt.Println("")
+	fmt.Println("Options:")
+	flag.PrintDefaults()
 }
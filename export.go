@@ -0,0 +1,254 @@
+package dpk
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+//=============================================================================
+// Exporters
+//
+// Exporters write the posts produced by importers to disk. They do not depend
+// on the provider the posts come from, so any import can be written in any
+// output format.
+
+// Exporter writes posts to an output directory.
+type Exporter interface {
+	Export(outputDir string, posts []Post) error
+}
+
+var exporters = make(map[string]Exporter)
+
+// RegisterExporter makes an exporter available for the given format name.
+// It is expected to be called from the init function of the exporter.
+func RegisterExporter(format string, exporter Exporter) {
+	exporters[format] = exporter
+}
+
+// GetExporter returns the exporter registered for format.
+func GetExporter(format string) (Exporter, error) {
+	exporter, ok := exporters[format]
+	if !ok {
+		return nil, fmt.Errorf("unknown output format: %s", format)
+	}
+	return exporter, nil
+}
+
+// Exporters returns the sorted list of formats with a registered exporter.
+func Exporters() []string {
+	var formats []string
+	for format := range exporters {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+func init() {
+	RegisterExporter("dpk", DPKExporter{})
+	RegisterExporter("json", JSONExporter{})
+}
+
+//=============================================================================
+// DPK layout
+
+// DPKExporter stores each post in its own directory, YYYY/MM/DD/NNN, with its
+// content in post.md, its metadata in metadata.json and its attachments. It
+// also generates an index page for each hashtag in the tags/ directory, the
+// SQLite index of all posts and their full-text search index.
+type DPKExporter struct {
+	// FrontMatter is the format of the metadata added at the top of post.md.
+	FrontMatter FrontMatter
+	// Incremental stores posts in directories named after their ID,
+	// YYYY/MM/DD/<Id>, and only writes the posts that are new or changed since
+	// the previous export, as recorded in the output directory manifest.
+	Incremental bool
+}
+
+// Export writes posts to outputDir in DPK layout.
+func (e DPKExporter) Export(outputDir string, posts []Post) error {
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return err
+	}
+	var m manifest
+	if e.Incremental {
+		var err error
+		if m, err = readManifest(outputDir); err != nil {
+			return err
+		}
+	}
+
+	var added, updated, unchanged int
+	for _, p := range numberPosts(posts) {
+		postDir := filepath.Join(p.dayDir(), p.number)
+		if e.Incremental {
+			postDir = filepath.Join(p.dayDir(), p.Id)
+		}
+
+		// Generate markdown and metadata for post
+		metadata := p.Metadata()
+		frontMatter, err := e.FrontMatter.render(metadata)
+		if err != nil {
+			return err
+		}
+		content := []byte(frontMatter + p.Content)
+		meta, err := json.Marshal(metadata)
+		if err != nil {
+			return err
+		}
+
+		if e.Incremental {
+			key := manifestKey(p.Post)
+			hash := postHash(content, meta, p.Attachments)
+			if m.unchanged(outputDir, key, filepath.ToSlash(postDir), hash) {
+				unchanged++
+				continue
+			}
+			if entry, ok := m.Posts[key]; ok {
+				updated++
+				// Post moved, for example to a different section
+				if entry.Path != filepath.ToSlash(postDir) {
+					if err = os.RemoveAll(filepath.Join(outputDir, filepath.FromSlash(entry.Path))); err != nil {
+						return err
+					}
+				}
+			} else {
+				added++
+			}
+			m.Posts[key] = manifestEntry{Path: filepath.ToSlash(postDir), Hash: hash, ExportedAt: time.Now().UTC()}
+		}
+
+		// Create directory for post
+		targetDir := filepath.Join(outputDir, postDir)
+		if err = os.MkdirAll(targetDir, 0755); err != nil {
+			return err
+		}
+		writeAttachments(p.Post, targetDir)
+		if err = ioutil.WriteFile(filepath.Join(targetDir, "post.md"), content, 0644); err != nil {
+			return err
+		}
+		if err = ioutil.WriteFile(filepath.Join(targetDir, "metadata.json"), meta, 0644); err != nil {
+			return err
+		}
+	}
+
+	if e.Incremental {
+		fmt.Printf("%d new, %d updated and %d unchanged post(s)\n", added, updated, unchanged)
+		if err := m.write(outputDir); err != nil {
+			return err
+		}
+	}
+	// Indexes cover all the posts of the output directory, including the ones
+	// from previous exports.
+	if err := writeTagIndex(outputDir); err != nil {
+		return err
+	}
+	if err := BuildIndex(outputDir); err != nil {
+		return err
+	}
+	return BuildSearchIndex(outputDir)
+}
+
+// numberedPost is a post with its sequence number among the posts of the same
+// day, in the same section.
+type numberedPost struct {
+	Post
+	number string
+}
+
+// numberPosts assigns a sequence number, NNN, to each post for its day.
+func numberPosts(posts []Post) []numberedPost {
+	// Number of posts for each day directory
+	postCount := make(map[string]int)
+	numbered := make([]numberedPost, len(posts))
+	for i, post := range posts {
+		p := numberedPost{Post: post}
+		postCount[p.dayDir()]++
+		p.number = fmt.Sprintf("%03d", postCount[p.dayDir()])
+		numbered[i] = p
+	}
+	return numbered
+}
+
+// dayDir returns the directory of the post day, in its section: YYYY/MM/DD.
+func (p Post) dayDir() string {
+	return filepath.Join(
+		p.Section,
+		fmt.Sprintf("%02d", p.CreatedAt.Year()),
+		fmt.Sprintf("%02d", p.CreatedAt.Month()),
+		fmt.Sprintf("%02d", p.CreatedAt.Day()))
+}
+
+// writeAttachments copies the post attachments to targetDir. Errors are
+// reported but do not stop the export.
+func writeAttachments(post Post, targetDir string) {
+	for _, attachment := range post.Attachments {
+		if err := writeAttachment(attachment, filepath.Join(targetDir, attachment.Filename)); err != nil {
+			fmt.Println("Error copying", attachment.Filename)
+		}
+	}
+}
+
+// writeAttachment copies the content of the attachment to dst. Any existing
+// file will be overwritten.
+func writeAttachment(attachment Attachment, dst string) error {
+	if attachment.Open == nil {
+		return fmt.Errorf("no content for attachment %s", attachment.Filename)
+	}
+	in, err := attachment.Open()
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	_, err = io.Copy(out, in)
+	if err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+//=============================================================================
+// JSON dump
+
+// JSONExporter writes all the posts to a single posts.json file. Attachments
+// are stored in the attachments/<Provider>/<Id> directory of each post.
+type JSONExporter struct{}
+
+// Export writes posts to outputDir as a JSON dump.
+func (JSONExporter) Export(outputDir string, posts []Post) error {
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return err
+	}
+	for _, post := range posts {
+		if len(post.Attachments) == 0 {
+			continue
+		}
+		targetDir := filepath.Join(outputDir, "attachments", post.Provider, post.Id)
+		if err := os.MkdirAll(targetDir, 0755); err != nil {
+			return err
+		}
+		writeAttachments(post, targetDir)
+	}
+
+	if posts == nil {
+		posts = []Post{}
+	}
+	data, err := json.MarshalIndent(posts, "", "\t")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(outputDir, "posts.json"), data, 0644)
+}
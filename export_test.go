@@ -0,0 +1,234 @@
+package dpk_test
+
+import (
+	"encoding/json"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/processone/dpk"
+)
+
+func TestTwitterExporters(t *testing.T) {
+	tests := []struct {
+		format   string
+		post     string
+		contains []string
+		media    string
+	}{
+		{
+			format:   "hugo",
+			post:     "content/posts/2022/10/31/002/index.md",
+			contains: []string{"---\ntype: microblog\n", "date: 2022-10-31T21:21:10Z\n---\n\n", "source: https://twitter.com/processone/status/1587200000000000000", "(1587200000000000000-FgXyZ12WAAEaBcD.jpg)"},
+			media:    "content/posts/2022/10/31/002/1587200000000000000-FgXyZ12WAAEaBcD.jpg",
+		},
+		{
+			format:   "jekyll",
+			post:     "_posts/2022-10-31-1587200000000000000.md",
+			contains: []string{"---\nlayout: post\ntype: microblog\n", "(/assets/twitter/1587200000000000000/1587200000000000000-FgXyZ12WAAEaBcD.jpg)"},
+			media:    "assets/twitter/1587200000000000000/1587200000000000000-FgXyZ12WAAEaBcD.jpg",
+		},
+		{
+			format:   "json",
+			post:     "posts.json",
+			contains: []string{`"SourceUrl": "https://twitter.com/processone/status/1587200000000000000"`},
+			media:    "attachments/twitter/1587200000000000000/1587200000000000000-FgXyZ12WAAEaBcD.jpg",
+		},
+	}
+
+	for _, tc := range tests {
+		exporter, err := dpk.GetExporter(tc.format)
+		if err != nil {
+			t.Errorf("%s exporter is not registered: %s", tc.format, err)
+			continue
+		}
+		outputDir := t.TempDir()
+		options := dpk.TwitterOptions{Exporter: exporter}
+		if err = dpk.TwitterToMD("fixtures/twitter-2022", outputDir, options); err != nil {
+			t.Errorf("%s: cannot convert archive: %s", tc.format, err)
+			continue
+		}
+		post, err := ioutil.ReadFile(filepath.Join(outputDir, tc.post))
+		if err != nil {
+			t.Errorf("%s: cannot read exported post: %s", tc.format, err)
+			continue
+		}
+		for _, s := range tc.contains {
+			if !strings.Contains(string(post), s) {
+				t.Errorf("%s: exported post does not contain '%s': %s", tc.format, s, post)
+			}
+		}
+		if _, err = os.Stat(filepath.Join(outputDir, tc.media)); err != nil {
+			t.Errorf("%s: media was not exported: %s", tc.format, err)
+		}
+	}
+}
+
+func TestJSONExporter(t *testing.T) {
+	exporter, _ := dpk.GetExporter("json")
+	outputDir := t.TempDir()
+	if err := dpk.TwitterToMD("fixtures/twitter-2022", outputDir, dpk.TwitterOptions{Exporter: exporter}); err != nil {
+		t.Errorf("Cannot convert archive: %s", err)
+		return
+	}
+	data, err := ioutil.ReadFile(filepath.Join(outputDir, "posts.json"))
+	if err != nil {
+		t.Errorf("Cannot read JSON dump: %s", err)
+		return
+	}
+	var posts []dpk.Post
+	if err = json.Unmarshal(data, &posts); err != nil {
+		t.Errorf("Cannot parse JSON dump: %s", err)
+		return
+	}
+	if len(posts) != 4 || posts[2].Id != "1587800000000000001" || !strings.Contains(posts[2].Content, "\n\n---\n\n") {
+		t.Errorf("Incorrect posts in JSON dump: %+v", posts)
+	}
+}
+
+func TestDPKFrontMatter(t *testing.T) {
+	tests := []struct {
+		frontMatter dpk.FrontMatter
+		expected    string
+	}{
+		{
+			frontMatter: dpk.YAMLFrontMatter,
+			expected: `---
+type: microblog
+lang: en
+source: https://twitter.com/processone/status/1587800000000000001
+aliases:
+- /processone/status/1587800000000000001
+- /processone/status/1587800000000000002
+- /processone/status/1587800000000000003
+date: 2022-11-02T10:00:00Z
+lastmod: 2022-11-02T10:07:00Z
+---
+
+`,
+		},
+		{
+			frontMatter: dpk.TOMLFrontMatter,
+			expected: `+++
+type = "microblog"
+lang = "en"
+source = "https://twitter.com/processone/status/1587800000000000001"
+aliases = ["/processone/status/1587800000000000001", "/processone/status/1587800000000000002", "/processone/status/1587800000000000003"]
+date = 2022-11-02T10:00:00Z
+lastmod = 2022-11-02T10:07:00Z
++++
+
+`,
+		},
+	}
+
+	for _, tc := range tests {
+		outputDir := t.TempDir()
+		options := dpk.TwitterOptions{Exporter: dpk.DPKExporter{FrontMatter: tc.frontMatter}}
+		if err := dpk.TwitterToMD("fixtures/twitter-2022", outputDir, options); err != nil {
+			t.Errorf("Cannot convert archive: %s", err)
+			continue
+		}
+		postDir := filepath.Join(outputDir, "2022", "11", "02", "001")
+		post, err := ioutil.ReadFile(filepath.Join(postDir, "post.md"))
+		if err != nil {
+			t.Errorf("Cannot read converted post: %s", err)
+			continue
+		}
+		if !strings.HasPrefix(string(post), tc.expected) {
+			t.Errorf("Incorrect front matter. Got: '%s' Expected: '%s'", post, tc.expected)
+		}
+
+		// Metadata file is still generated, with the same content
+		data, err := ioutil.ReadFile(filepath.Join(postDir, "metadata.json"))
+		if err != nil {
+			t.Errorf("Cannot read metadata: %s", err)
+			continue
+		}
+		var metadata dpk.Metadata
+		if err = json.Unmarshal(data, &metadata); err != nil {
+			t.Errorf("Cannot parse metadata: %s", err)
+			continue
+		}
+		if len(metadata.Aliases) != 3 || metadata.UpdatedAt == nil {
+			t.Errorf("Incorrect metadata: %+v", metadata)
+		}
+	}
+}
+
+func TestIncrementalExport(t *testing.T) {
+	// Load the archive in memory, to be able to update it
+	archive := fstest.MapFS{}
+	err := fs.WalkDir(os.DirFS("fixtures/twitter-2022"), ".", func(file string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := ioutil.ReadFile(filepath.Join("fixtures/twitter-2022", file))
+		archive[file] = &fstest.MapFile{Data: data}
+		return err
+	})
+	if err != nil {
+		t.Errorf("Cannot read archive: %s", err)
+		return
+	}
+
+	outputDir := t.TempDir()
+	options := dpk.TwitterOptions{Exporter: dpk.DPKExporter{Incremental: true}}
+	if err = dpk.TwitterArchiveToMD(archive, outputDir, options); err != nil {
+		t.Errorf("Cannot convert archive: %s", err)
+		return
+	}
+	// Post directories are named after the post ID
+	photoPost := filepath.Join(outputDir, "2022", "10", "31", "1587200000000000000", "post.md")
+	linkPost := filepath.Join(outputDir, "2022", "10", "31", "1587129473619230720", "post.md")
+	for _, file := range []string{photoPost, linkPost, filepath.Join(outputDir, dpk.ManifestFile)} {
+		if _, err = os.Stat(file); err != nil {
+			t.Errorf("Missing file: %s", err)
+			return
+		}
+	}
+	past := time.Now().Add(-time.Hour).Truncate(time.Second)
+	for _, file := range []string{photoPost, linkPost} {
+		if err = os.Chtimes(file, past, past); err != nil {
+			t.Errorf("Cannot change file time: %s", err)
+			return
+		}
+	}
+
+	// Newer archive, with an edited tweet and a new tweet
+	data := string(archive["data/tweets.js"].Data)
+	data = strings.Replace(data, "Paris, by the Seine.", "Paris, by the Seine river.", 1)
+	data = strings.TrimSpace(data)
+	data = strings.TrimSuffix(data, "]") + `, {
+  "tweet" : {
+    "id_str" : "1590000000000000000",
+    "created_at" : "Wed Nov 09 10:00:00 +0000 2022",
+    "full_text" : "A new tweet",
+    "lang" : "en"
+  }
+} ]`
+	archive["data/tweets.js"] = &fstest.MapFile{Data: []byte(data)}
+	if err = dpk.TwitterArchiveToMD(archive, outputDir, options); err != nil {
+		t.Errorf("Cannot convert updated archive: %s", err)
+		return
+	}
+
+	if info, err := os.Stat(linkPost); err != nil || !info.ModTime().Equal(past) {
+		t.Errorf("Unchanged post should not be written again: %v %s", info, err)
+	}
+	if post, err := ioutil.ReadFile(photoPost); err != nil || !strings.Contains(string(post), "Seine river") {
+		t.Errorf("Changed post was not updated: %s %s", post, err)
+	}
+	if _, err = os.Stat(filepath.Join(outputDir, "2022", "11", "09", "1590000000000000000", "post.md")); err != nil {
+		t.Errorf("New post was not written: %s", err)
+	}
+	results, err := dpk.Search(outputDir, "new tweet", dpk.SearchOptions{})
+	if err != nil || len(results) != 1 {
+		t.Errorf("New post is not indexed: %v %s", results, err)
+	}
+}
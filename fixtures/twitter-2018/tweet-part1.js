@@ -0,0 +1,88 @@
+window.YTD.tweet.part1 = [ {
+  "retweeted" : false,
+  "source" : "<a href=\"http://twitter.com/download/iphone\" rel=\"nofollow\">Twitter for iPhone</a>",
+  "entities" : {
+    "hashtags" : [ ],
+    "symbols" : [ ],
+    "user_mentions" : [ ],
+    "urls" : [ ]
+  },
+  "display_text_range" : [ "0", "19" ],
+  "favorite_count" : "12",
+  "id_str" : "817366093016002560",
+  "truncated" : false,
+  "retweet_count" : "4",
+  "id" : "817366093016002560",
+  "created_at" : "Fri Jan 06 13:06:10 +0000 2017",
+  "favorited" : false,
+  "full_text" : "Happy new year 2017!",
+  "lang" : "en"
+}, {
+  "retweeted" : false,
+  "source" : "<a href=\"http://twitter.com\" rel=\"nofollow\">Twitter Web Client</a>",
+  "entities" : {
+    "hashtags" : [ ],
+    "symbols" : [ ],
+    "user_mentions" : [ {
+      "name" : "Mickaël Rémond",
+      "screen_name" : "mickael",
+      "indices" : [ "3", "11" ],
+      "id_str" : "1155",
+      "id" : "1155"
+    } ],
+    "urls" : [ {
+      "url" : "https://t.co/AbCdEfGhIj",
+      "expanded_url" : "https://www.process-one.net/en/ejabberd/",
+      "display_url" : "process-one.net/en/ejabberd/",
+      "indices" : [ "120", "143" ]
+    } ]
+  },
+  "display_text_range" : [ "0", "139" ],
+  "favorite_count" : "0",
+  "id_str" : "699182563473170432",
+  "truncated" : false,
+  "retweet_count" : "0",
+  "id" : "699182563473170432",
+  "created_at" : "Mon Feb 15 09:00:00 +0000 2016",
+  "favorited" : false,
+  "full_text" : "RT @mickael: ejabberd is a robust, scalable and extensible realtime platform, built with Erlang/OTP. Read our new post: https://t.co/AbCdE…",
+  "lang" : "en"
+}, {
+  "retweeted" : false,
+  "source" : "<a href=\"http://twitter.com\" rel=\"nofollow\">Twitter Web Client</a>",
+  "entities" : {
+    "hashtags" : [ ],
+    "symbols" : [ ],
+    "user_mentions" : [ ],
+    "urls" : [ ]
+  },
+  "display_text_range" : [ "0", "26" ],
+  "favorite_count" : "0",
+  "id_str" : "699545000000000000",
+  "truncated" : false,
+  "retweet_count" : "0",
+  "id" : "699545000000000000",
+  "created_at" : "Tue Feb 16 09:00:00 +0000 2016",
+  "favorited" : false,
+  "full_text" : "Well, that was unexpected…",
+  "lang" : "en"
+}, {
+  "retweeted" : false,
+  "source" : "<a href=\"http://twitter.com\" rel=\"nofollow\">Twitter Web Client</a>",
+  "entities" : {
+    "hashtags" : [ ],
+    "symbols" : [ ],
+    "user_mentions" : [ ],
+    "urls" : [ ]
+  },
+  "display_text_range" : [ "0", "74" ],
+  "favorite_count" : "0",
+  "id_str" : "699907000000000000",
+  "truncated" : true,
+  "retweet_count" : "0",
+  "id" : "699907000000000000",
+  "created_at" : "Wed Feb 17 09:00:00 +0000 2016",
+  "favorited" : false,
+  "full_text" : "This text was cut by a third-party client and the rest of it is lost fore…",
+  "lang" : "en"
+} ]
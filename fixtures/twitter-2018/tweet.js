@@ -0,0 +1,68 @@
+window.YTD.tweet.part0 = [ {
+  "retweeted" : false,
+  "source" : "<a href=\"http://twitter.com\" rel=\"nofollow\">Twitter Web Client</a>",
+  "entities" : {
+    "hashtags" : [ ],
+    "symbols" : [ ],
+    "user_mentions" : [ ],
+    "urls" : [ {
+      "url" : "https://t.co/aBcDeFgHiJ",
+      "expanded_url" : "https://www.process-one.net/blog/",
+      "display_url" : "process-one.net/blog/",
+      "indices" : [ "22", "45" ]
+    } ]
+  },
+  "display_text_range" : [ "0", "45" ],
+  "favorite_count" : "3",
+  "id_str" : "1078253946104922112",
+  "truncated" : false,
+  "retweet_count" : "1",
+  "id" : "1078253946104922112",
+  "created_at" : "Thu Dec 27 11:02:03 +0000 2018",
+  "favorited" : false,
+  "full_text" : "New post on our blog: https://t.co/aBcDeFgHiJ",
+  "lang" : "en"
+}, {
+  "retweeted" : false,
+  "source" : "<a href=\"http://twitter.com\" rel=\"nofollow\">Twitter Web Client</a>",
+  "entities" : {
+    "hashtags" : [ ],
+    "symbols" : [ ],
+    "user_mentions" : [ ],
+    "urls" : [ ],
+    "media" : [ {
+      "expanded_url" : "https://twitter.com/processone/status/1078253946104922113/photo/1",
+      "indices" : [ "30", "53" ],
+      "url" : "https://t.co/xYzAbCdEfG",
+      "media_url" : "http://pbs.twimg.com/media/DvYx1YlWsAAbCdE.jpg",
+      "id_str" : "1078253940000000000",
+      "id" : "1078253940000000000",
+      "media_url_https" : "https://pbs.twimg.com/media/DvYx1YlWsAAbCdE.jpg",
+      "type" : "photo",
+      "display_url" : "pic.twitter.com/xYzAbCdEfG"
+    } ]
+  },
+  "extended_entities" : {
+    "media" : [ {
+      "expanded_url" : "https://twitter.com/processone/status/1078253946104922113/photo/1",
+      "indices" : [ "30", "53" ],
+      "url" : "https://t.co/xYzAbCdEfG",
+      "media_url" : "http://pbs.twimg.com/media/DvYx1YlWsAAbCdE.jpg",
+      "id_str" : "1078253940000000000",
+      "id" : "1078253940000000000",
+      "media_url_https" : "https://pbs.twimg.com/media/DvYx1YlWsAAbCdE.jpg",
+      "type" : "photo",
+      "display_url" : "pic.twitter.com/xYzAbCdEfG"
+    } ]
+  },
+  "display_text_range" : [ "0", "29" ],
+  "favorite_count" : "0",
+  "id_str" : "1078253946104922113",
+  "truncated" : false,
+  "retweet_count" : "0",
+  "id" : "1078253946104922113",
+  "created_at" : "Thu Dec 27 12:00:00 +0000 2018",
+  "favorited" : false,
+  "full_text" : "Second tweet of the day.\nBye! https://t.co/xYzAbCdEfG",
+  "lang" : "en"
+} ]
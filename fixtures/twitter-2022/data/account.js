@@ -0,0 +1,12 @@
+window.YTD.account.part0 = [
+  {
+    "account" : {
+      "email" : "contact@example.com",
+      "createdVia" : "web",
+      "username" : "processone",
+      "accountId" : "14204453",
+      "createdAt" : "2008-03-24T07:45:16.000Z",
+      "accountDisplayName" : "ProcessOne"
+    }
+  }
+]
@@ -0,0 +1,365 @@
+window.YTD.tweets.part0 = [
+  {
+    "tweet" : {
+      "edit_info" : {
+        "initial" : {
+          "editTweetIds" : [
+            "1587129473619230720"
+          ],
+          "editableUntil" : "2022-10-31T17:38:49.000Z",
+          "editsRemaining" : "5",
+          "isEditEligible" : true
+        }
+      },
+      "retweeted" : false,
+      "source" : "<a href=\"https://mobile.twitter.com\" rel=\"nofollow\">Twitter Web App</a>",
+      "entities" : {
+        "hashtags" : [ ],
+        "symbols" : [ ],
+        "user_mentions" : [ ],
+        "urls" : [
+          {
+            "url" : "https://t.co/QwErTyUiOp",
+            "expanded_url" : "https://www.ejabberd.im/",
+            "display_url" : "ejabberd.im",
+            "indices" : [
+              "32",
+              "55"
+            ]
+          }
+        ]
+      },
+      "display_text_range" : [
+        "0",
+        "55"
+      ],
+      "favorite_count" : "7",
+      "id_str" : "1587129473619230720",
+      "truncated" : false,
+      "retweet_count" : "2",
+      "id" : "1587129473619230720",
+      "created_at" : "Mon Oct 31 16:38:49 +0000 2022",
+      "favorited" : false,
+      "full_text" : "ejabberd 22.10 is out. Details: https://t.co/QwErTyUiOp",
+      "lang" : "en"
+    }
+  },
+  {
+    "tweet" : {
+      "edit_info" : {
+        "initial" : {
+          "editTweetIds" : [
+            "1587200000000000000"
+          ],
+          "editableUntil" : "2022-10-31T22:21:10.000Z",
+          "editsRemaining" : "5",
+          "isEditEligible" : false
+        }
+      },
+      "retweeted" : false,
+      "source" : "<a href=\"http://twitter.com/download/android\" rel=\"nofollow\">Twitter for Android</a>",
+      "entities" : {
+        "hashtags" : [ ],
+        "symbols" : [ ],
+        "user_mentions" : [ ],
+        "urls" : [ ],
+        "media" : [
+          {
+            "expanded_url" : "https://twitter.com/processone/status/1587200000000000000/photo/1",
+            "indices" : [
+              "21",
+              "44"
+            ],
+            "url" : "https://t.co/ZxCvBnMaSd",
+            "media_url" : "http://pbs.twimg.com/media/FgXyZ12WAAEaBcD.jpg",
+            "id_str" : "1587199990000000000",
+            "id" : "1587199990000000000",
+            "media_url_https" : "https://pbs.twimg.com/media/FgXyZ12WAAEaBcD.jpg",
+            "sizes" : {
+              "large" : {
+                "w" : "1",
+                "h" : "1",
+                "resize" : "fit"
+              }
+            },
+            "type" : "photo",
+            "display_url" : "pic.twitter.com/ZxCvBnMaSd"
+          }
+        ]
+      },
+      "display_text_range" : [
+        "0",
+        "20"
+      ],
+      "favorite_count" : "15",
+      "id_str" : "1587200000000000000",
+      "truncated" : false,
+      "retweet_count" : "0",
+      "id" : "1587200000000000000",
+      "possibly_sensitive" : false,
+      "created_at" : "Mon Oct 31 21:21:10 +0000 2022",
+      "favorited" : false,
+      "full_text" : "Paris, by the Seine. https://t.co/ZxCvBnMaSd",
+      "lang" : "en",
+      "extended_entities" : {
+        "media" : [
+          {
+            "expanded_url" : "https://twitter.com/processone/status/1587200000000000000/photo/1",
+            "indices" : [
+              "21",
+              "44"
+            ],
+            "url" : "https://t.co/ZxCvBnMaSd",
+            "media_url" : "http://pbs.twimg.com/media/FgXyZ12WAAEaBcD.jpg",
+            "id_str" : "1587199990000000000",
+            "id" : "1587199990000000000",
+            "media_url_https" : "https://pbs.twimg.com/media/FgXyZ12WAAEaBcD.jpg",
+            "sizes" : {
+              "large" : {
+                "w" : "1",
+                "h" : "1",
+                "resize" : "fit"
+              }
+            },
+            "ext_alt_text" : "The Seine river in Paris",
+            "type" : "photo",
+            "display_url" : "pic.twitter.com/ZxCvBnMaSd"
+          }
+        ]
+      }
+    }
+  },
+  {
+    "tweet" : {
+      "edit_info" : {
+        "initial" : {
+          "editTweetIds" : [
+            "1587800000000000001"
+          ],
+          "editableUntil" : "2022-11-02T10:30:00.000Z",
+          "editsRemaining" : "5",
+          "isEditEligible" : false
+        }
+      },
+      "retweeted" : false,
+      "source" : "<a href=\"https://mobile.twitter.com\" rel=\"nofollow\">Twitter Web App</a>",
+      "entities" : {
+        "hashtags" : [ ],
+        "symbols" : [ ],
+        "user_mentions" : [ ],
+        "urls" : [ ]
+      },
+      "display_text_range" : [
+        "0",
+        "44"
+      ],
+      "favorite_count" : "0",
+      "id_str" : "1587800000000000001",
+      "truncated" : false,
+      "retweet_count" : "0",
+      "id" : "1587800000000000001",
+      "created_at" : "Wed Nov 02 10:00:00 +0000 2022",
+      "favorited" : false,
+      "full_text" : "Let's talk about XMPP scalability. A thread:",
+      "lang" : "en"
+    }
+  },
+  {
+    "tweet" : {
+      "edit_info" : {
+        "initial" : {
+          "editTweetIds" : [
+            "1587800000000000002"
+          ],
+          "editableUntil" : "2022-11-02T10:30:00.000Z",
+          "editsRemaining" : "5",
+          "isEditEligible" : false
+        }
+      },
+      "retweeted" : false,
+      "source" : "<a href=\"https://mobile.twitter.com\" rel=\"nofollow\">Twitter Web App</a>",
+      "entities" : {
+        "hashtags" : [ ],
+        "symbols" : [ ],
+        "user_mentions" : [
+          {
+            "name" : "ProcessOne",
+            "screen_name" : "processone",
+            "indices" : [
+              "0",
+              "11"
+            ],
+            "id_str" : "14204453",
+            "id" : "14204453"
+          }
+        ],
+        "urls" : [ ]
+      },
+      "display_text_range" : [
+        "12",
+        "42"
+      ],
+      "favorite_count" : "0",
+      "id_str" : "1587800000000000002",
+      "truncated" : false,
+      "retweet_count" : "0",
+      "id" : "1587800000000000002",
+      "created_at" : "Wed Nov 02 10:05:00 +0000 2022",
+      "favorited" : false,
+      "full_text" : "@processone First, clustering is built in.",
+      "lang" : "en",
+      "in_reply_to_status_id_str" : "1587800000000000001",
+      "in_reply_to_status_id" : "1587800000000000001",
+      "in_reply_to_user_id" : "14204453",
+      "in_reply_to_user_id_str" : "14204453",
+      "in_reply_to_screen_name" : "processone"
+    }
+  },
+  {
+    "tweet" : {
+      "edit_info" : {
+        "initial" : {
+          "editTweetIds" : [
+            "1587800000000000003"
+          ],
+          "editableUntil" : "2022-11-02T10:30:00.000Z",
+          "editsRemaining" : "5",
+          "isEditEligible" : false
+        }
+      },
+      "retweeted" : false,
+      "source" : "<a href=\"https://mobile.twitter.com\" rel=\"nofollow\">Twitter Web App</a>",
+      "entities" : {
+        "hashtags" : [ ],
+        "symbols" : [ ],
+        "user_mentions" : [
+          {
+            "name" : "ProcessOne",
+            "screen_name" : "processone",
+            "indices" : [
+              "0",
+              "11"
+            ],
+            "id_str" : "14204453",
+            "id" : "14204453"
+          }
+        ],
+        "urls" : [ ]
+      },
+      "display_text_range" : [
+        "12",
+        "53"
+      ],
+      "favorite_count" : "0",
+      "id_str" : "1587800000000000003",
+      "truncated" : false,
+      "retweet_count" : "0",
+      "id" : "1587800000000000003",
+      "created_at" : "Wed Nov 02 10:07:00 +0000 2022",
+      "favorited" : false,
+      "full_text" : "@processone Second, you can shard users across nodes.",
+      "lang" : "en",
+      "in_reply_to_status_id_str" : "1587800000000000002",
+      "in_reply_to_status_id" : "1587800000000000002",
+      "in_reply_to_user_id" : "14204453",
+      "in_reply_to_user_id_str" : "14204453",
+      "in_reply_to_screen_name" : "processone"
+    }
+  },
+  {
+    "tweet" : {
+      "edit_info" : {
+        "initial" : {
+          "editTweetIds" : [
+            "1588100000000000000"
+          ],
+          "editableUntil" : "2022-11-02T10:30:00.000Z",
+          "editsRemaining" : "5",
+          "isEditEligible" : false
+        }
+      },
+      "retweeted" : false,
+      "source" : "<a href=\"https://mobile.twitter.com\" rel=\"nofollow\">Twitter Web App</a>",
+      "entities" : {
+        "hashtags" : [ ],
+        "symbols" : [ ],
+        "user_mentions" : [
+          {
+            "name" : "Mickaël Rémond",
+            "screen_name" : "mickael",
+            "indices" : [
+              "0",
+              "8"
+            ],
+            "id_str" : "1155",
+            "id" : "1155"
+          }
+        ],
+        "urls" : [ ]
+      },
+      "display_text_range" : [
+        "9",
+        "38"
+      ],
+      "favorite_count" : "0",
+      "id_str" : "1588100000000000000",
+      "truncated" : false,
+      "retweet_count" : "0",
+      "id" : "1588100000000000000",
+      "created_at" : "Thu Nov 03 08:00:00 +0000 2022",
+      "favorited" : false,
+      "full_text" : "@mickael Thanks, we will fix the docs!",
+      "lang" : "en",
+      "in_reply_to_status_id_str" : "1588000000000000000",
+      "in_reply_to_status_id" : "1588000000000000000",
+      "in_reply_to_user_id" : "1155",
+      "in_reply_to_user_id_str" : "1155",
+      "in_reply_to_screen_name" : "mickael"
+    }
+  },
+  {
+    "tweet" : {
+      "edit_info" : {
+        "initial" : {
+          "editTweetIds" : [
+            "1588500000000000000"
+          ],
+          "editableUntil" : "2022-11-04T10:30:00.000Z",
+          "editsRemaining" : "5",
+          "isEditEligible" : false
+        }
+      },
+      "retweeted" : false,
+      "source" : "<a href=\"https://mobile.twitter.com\" rel=\"nofollow\">Twitter Web App</a>",
+      "entities" : {
+        "hashtags" : [ ],
+        "symbols" : [ ],
+        "user_mentions" : [ ],
+        "urls" : [
+          {
+            "url" : "https://t.co/QuOtEtWeEt",
+            "expanded_url" : "https://twitter.com/processone/status/1587200000000000000",
+            "display_url" : "twitter.com/processone/st…",
+            "indices" : [
+              "31",
+              "54"
+            ]
+          }
+        ]
+      },
+      "display_text_range" : [
+        "0",
+        "54"
+      ],
+      "favorite_count" : "3",
+      "id_str" : "1588500000000000000",
+      "truncated" : false,
+      "retweet_count" : "0",
+      "id" : "1588500000000000000",
+      "created_at" : "Fri Nov 04 10:00:00 +0000 2022",
+      "favorited" : false,
+      "full_text" : "Still our most popular release https://t.co/QuOtEtWeEt",
+      "lang" : "en"
+    }
+  }
+]
@@ -0,0 +1,63 @@
+package dpk
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+)
+
+//=============================================================================
+// Front matter
+//
+// Front matter is a metadata block at the top of a Markdown file, used by
+// static site generators like Hugo or Jekyll. It is generated from the post
+// Metadata, so that it stays in sync with metadata.json.
+
+// FrontMatter defines the format of the front matter added to posts.
+type FrontMatter int
+
+const (
+	// NoFrontMatter keeps post content without front matter.
+	NoFrontMatter FrontMatter = iota
+	// YAMLFrontMatter adds a YAML front matter, between --- lines.
+	YAMLFrontMatter
+	// TOMLFrontMatter adds a TOML front matter, between +++ lines.
+	TOMLFrontMatter
+)
+
+// ParseFrontMatter returns the front matter format matching name: none, yaml
+// or toml.
+func ParseFrontMatter(name string) (FrontMatter, error) {
+	switch name {
+	case "", "none":
+		return NoFrontMatter, nil
+	case "yaml":
+		return YAMLFrontMatter, nil
+	case "toml":
+		return TOMLFrontMatter, nil
+	}
+	return NoFrontMatter, fmt.Errorf("unknown front matter format: %s", name)
+}
+
+// render returns the front matter block for data, usually the post Metadata.
+// The block is followed by an empty line, so that content can be appended to
+// it directly.
+func (f FrontMatter) render(data interface{}) (string, error) {
+	switch f {
+	case YAMLFrontMatter:
+		out, err := yaml.Marshal(data)
+		if err != nil {
+			return "", err
+		}
+		return "---\n" + string(out) + "---\n\n", nil
+	case TOMLFrontMatter:
+		var out bytes.Buffer
+		if err := toml.NewEncoder(&out).Encode(data); err != nil {
+			return "", err
+		}
+		return "+++\n" + out.String() + "+++\n\n", nil
+	}
+	return "", nil
+}
@@ -1,6 +1,11 @@
 module github.com/processone/dpk
 
+go 1.16
+
 require (
+	github.com/BurntSushi/toml v1.2.1
 	github.com/microcosm-cc/bluemonday v1.0.2
-	golang.org/x/net v0.0.0-20181220203305-927f97764cc3
+	golang.org/x/net v0.0.0-20201021035429-f5854403a974
+	gopkg.in/yaml.v2 v2.4.0
+	modernc.org/sqlite v1.14.8
 )
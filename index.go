@@ -0,0 +1,204 @@
+package dpk
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	// Pure Go SQLite driver, registered as "sqlite"
+	_ "modernc.org/sqlite"
+)
+
+//=============================================================================
+// SQLite index
+//
+// The index is a SQLite database at the root of the output directory, with a
+// row for each post and its media, links, tags and mentions. It is always
+// generated from the metadata.json files of the output tree, so it can be
+// rebuilt at any time.
+
+// IndexFile is the name of the SQLite index, in the output directory.
+const IndexFile = "dpk.sqlite"
+
+const indexSchema = `
+CREATE TABLE posts (
+	id             INTEGER PRIMARY KEY,
+	path           TEXT NOT NULL UNIQUE,
+	provider       TEXT NOT NULL,
+	post_id        TEXT NOT NULL,
+	type           TEXT NOT NULL,
+	lang           TEXT,
+	source_url     TEXT,
+	in_reply_to    TEXT,
+	created_at     TEXT NOT NULL,
+	updated_at     TEXT,
+	favorite_count INTEGER,
+	repost_count   INTEGER,
+	client         TEXT,
+	client_url     TEXT
+);
+CREATE INDEX posts_created_at ON posts (created_at);
+
+CREATE TABLE media (
+	post         INTEGER NOT NULL REFERENCES posts (id),
+	file         TEXT NOT NULL,
+	type         TEXT NOT NULL,
+	original_url TEXT,
+	width        INTEGER,
+	height       INTEGER,
+	alt_text     TEXT
+);
+
+CREATE TABLE links (
+	post INTEGER NOT NULL REFERENCES posts (id),
+	url  TEXT NOT NULL
+);
+CREATE INDEX links_url ON links (url);
+
+CREATE TABLE tags (
+	post INTEGER NOT NULL REFERENCES posts (id),
+	tag  TEXT NOT NULL
+);
+CREATE INDEX tags_tag ON tags (tag COLLATE NOCASE);
+
+CREATE TABLE mentions (
+	post   INTEGER NOT NULL REFERENCES posts (id),
+	handle TEXT NOT NULL,
+	name   TEXT,
+	url    TEXT
+);
+CREATE INDEX mentions_handle ON mentions (handle COLLATE NOCASE);
+`
+
+// BuildIndex creates the SQLite index of the posts stored in outputDir, from
+// their metadata.json files. An existing index is replaced.
+func BuildIndex(outputDir string) error {
+	// The index is built in a temporary file, so that a failure does not leave
+	// a partial index.
+	indexFile := filepath.Join(outputDir, IndexFile)
+	tmpFile := indexFile + ".tmp"
+	if err := os.Remove(tmpFile); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	db, err := sql.Open("sqlite", tmpFile)
+	if err != nil {
+		return err
+	}
+	if err = populateIndex(db, outputDir); err != nil {
+		db.Close()
+		os.Remove(tmpFile)
+		return err
+	}
+	if err = db.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile, indexFile)
+}
+
+func populateIndex(db *sql.DB, outputDir string) error {
+	if _, err := db.Exec(indexSchema); err != nil {
+		return err
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = walkPosts(outputDir, func(postDir string, metadata Metadata) error {
+		return indexPost(tx, postDir, metadata)
+	})
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// walkPosts calls fn for each post stored in outputDir, with the post
+// directory, relative to outputDir, and the post metadata read from its
+// metadata.json file.
+func walkPosts(outputDir string, fn func(postDir string, metadata Metadata) error) error {
+	return filepath.WalkDir(outputDir, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || d.Name() != "metadata.json" {
+			return nil
+		}
+		postDir, err := filepath.Rel(outputDir, filepath.Dir(file))
+		if err != nil {
+			return err
+		}
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		var metadata Metadata
+		if err = json.Unmarshal(data, &metadata); err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		return fn(filepath.ToSlash(postDir), metadata)
+	})
+}
+
+// indexPost adds the post stored in postDir, relative to the output directory,
+// to the index.
+func indexPost(tx *sql.Tx, postDir string, metadata Metadata) error {
+	var updatedAt, client, clientUrl sql.NullString
+	if metadata.UpdatedAt != nil {
+		updatedAt = sql.NullString{String: indexTime(*metadata.UpdatedAt), Valid: true}
+	}
+	if metadata.Client != nil {
+		client = sql.NullString{String: metadata.Client.Name, Valid: true}
+		clientUrl = sql.NullString{String: metadata.Client.Url, Valid: metadata.Client.Url != ""}
+	}
+
+	res, err := tx.Exec(`INSERT INTO posts (path, provider, post_id, type, lang, source_url, in_reply_to,
+		created_at, updated_at, favorite_count, repost_count, client, client_url)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		postDir, metadata.Provider, metadata.Id, metadata.Type, metadata.Lang, metadata.SourceUrl, metadata.InReplyTo,
+		indexTime(metadata.CreatedAt), updatedAt, metadata.FavoriteCount, metadata.RepostCount, client, clientUrl)
+	if err != nil {
+		return err
+	}
+	post, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	for _, m := range metadata.Media {
+		if _, err = tx.Exec(`INSERT INTO media (post, file, type, original_url, width, height, alt_text)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			post, m.File, m.Type, m.OriginalUrl, m.Width, m.Height, m.AltText); err != nil {
+			return err
+		}
+	}
+	for _, link := range metadata.Links {
+		if _, err = tx.Exec(`INSERT INTO links (post, url) VALUES (?, ?)`, post, link); err != nil {
+			return err
+		}
+	}
+	for _, tag := range metadata.HashTags {
+		if _, err = tx.Exec(`INSERT INTO tags (post, tag) VALUES (?, ?)`, post, tag); err != nil {
+			return err
+		}
+	}
+	for _, m := range metadata.Mentions {
+		if _, err = tx.Exec(`INSERT INTO mentions (post, handle, name, url) VALUES (?, ?, ?, ?)`,
+			post, m.Handle, m.Name, m.Url); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// indexTime formats dates as UTC RFC 3339 strings, which sort chronologically.
+func indexTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
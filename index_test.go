@@ -0,0 +1,64 @@
+package dpk_test
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/processone/dpk"
+)
+
+func TestIndex(t *testing.T) {
+	outputDir := t.TempDir()
+	if err := dpk.TwitterToMD("fixtures/twitter-2022", outputDir, dpk.TwitterOptions{Replies: dpk.KeepReplies}); err != nil {
+		t.Errorf("Cannot convert archive: %s", err)
+		return
+	}
+
+	count := func(query string, args ...interface{}) int {
+		db, err := sql.Open("sqlite", filepath.Join(outputDir, dpk.IndexFile))
+		if err != nil {
+			t.Errorf("Cannot open index: %s", err)
+			return -1
+		}
+		defer db.Close()
+		var n int
+		if err = db.QueryRow(query, args...).Scan(&n); err != nil {
+			t.Errorf("Cannot query index: %s", err)
+			return -1
+		}
+		return n
+	}
+
+	if n := count(`SELECT count(*) FROM posts`); n != 5 {
+		t.Errorf("Incorrect number of indexed posts: %d", n)
+	}
+	if n := count(`SELECT count(*) FROM posts JOIN media ON media.post = posts.id
+		WHERE posts.path = ? AND media.alt_text = ?`, "2022/10/31/002", "The Seine river in Paris"); n != 1 {
+		t.Errorf("Media is not indexed")
+	}
+	if n := count(`SELECT count(*) FROM posts JOIN mentions ON mentions.post = posts.id
+		WHERE posts.type = 'reply' AND mentions.handle = ?`, "mickael"); n != 1 {
+		t.Errorf("Mention is not indexed")
+	}
+	if n := count(`SELECT count(*) FROM links WHERE url = ?`, "https://twitter.com/processone/status/1587200000000000000"); n != 1 {
+		t.Errorf("Link is not indexed")
+	}
+
+	// Index can be rebuilt from the output tree
+	if err := os.RemoveAll(filepath.Join(outputDir, "2022", "11", "03")); err != nil {
+		t.Errorf("Cannot remove post: %s", err)
+		return
+	}
+	if err := dpk.BuildIndex(outputDir); err != nil {
+		t.Errorf("Cannot rebuild index: %s", err)
+		return
+	}
+	if n := count(`SELECT count(*) FROM posts`); n != 4 {
+		t.Errorf("Incorrect number of posts after rebuild: %d", n)
+	}
+	if n := count(`SELECT count(*) FROM mentions`); n != 0 {
+		t.Errorf("Incorrect number of mentions after rebuild: %d", n)
+	}
+}
@@ -0,0 +1,94 @@
+package dpk
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+//=============================================================================
+// Manifest
+//
+// In incremental mode, the manifest records the posts already exported to the
+// output directory, with a hash of their content. It is used to only write new
+// and changed posts when importing a newer archive into the same directory.
+
+// ManifestFile is the name of the manifest of exported posts, in the output
+// directory.
+const ManifestFile = "manifest.json"
+
+type manifest struct {
+	// Posts are the exported posts, by provider and ID: <Provider>/<Id>.
+	Posts map[string]manifestEntry
+}
+
+type manifestEntry struct {
+	// Path is the post directory, relative to the output directory.
+	Path string
+	// Hash is the SHA-256 hash of the post files content.
+	Hash       string
+	ExportedAt time.Time
+}
+
+// readManifest returns the manifest stored in outputDir, or an empty manifest
+// if there is none.
+func readManifest(outputDir string) (manifest, error) {
+	m := manifest{Posts: make(map[string]manifestEntry)}
+	data, err := ioutil.ReadFile(filepath.Join(outputDir, ManifestFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return m, err
+	}
+	if err = json.Unmarshal(data, &m); err != nil {
+		return m, err
+	}
+	if m.Posts == nil {
+		m.Posts = make(map[string]manifestEntry)
+	}
+	return m, nil
+}
+
+func (m manifest) write(outputDir string) error {
+	data, err := json.MarshalIndent(m, "", "\t")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(outputDir, ManifestFile), data, 0644)
+}
+
+func manifestKey(post Post) string {
+	return post.Provider + "/" + post.Id
+}
+
+// postHash returns the hash of the files generated for a post: its content, its
+// metadata and the names of its attachments.
+func postHash(content, metadata []byte, attachments []Attachment) string {
+	h := sha256.New()
+	h.Write(content)
+	h.Write([]byte{0})
+	h.Write(metadata)
+	for _, a := range attachments {
+		h.Write([]byte{0})
+		h.Write([]byte(a.Filename))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// unchanged returns true if the post was already exported to postDir, with the
+// same content, and its directory still exists.
+func (m manifest) unchanged(outputDir, key, postDir, hash string) bool {
+	entry, ok := m.Posts[key]
+	if !ok || entry.Path != postDir || entry.Hash != hash {
+		return false
+	}
+	_, err := os.Stat(filepath.Join(outputDir, filepath.FromSlash(postDir), "post.md"))
+	return err == nil
+}
@@ -0,0 +1,215 @@
+package semweb
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+//=============================================================================
+// Link cache
+// Persist the result of link resolution across runs
+
+// CacheEntry is the result of the resolution of a URL.
+type CacheEntry struct {
+	// Url is the resolved URL, used as cache key.
+	Url string
+	// FinalUrl is the URL reached after following redirects.
+	FinalUrl string `json:",omitempty"`
+	// Status is the HTTP status code of the last response.
+	Status int
+	// Title is the title of the final page, when available.
+	Title string `json:",omitempty"`
+	// Description, SiteName and Image describe the final page, when
+	// available. Image is the URL of the image representing the page.
+	Description string `json:",omitempty"`
+	SiteName    string `json:",omitempty"`
+	Image       string `json:",omitempty"`
+	// Canonical is the canonical URL declared by the final page, if any.
+	Canonical string `json:",omitempty"`
+	// OEmbedUrl is the oEmbed representation URL declared by the final page,
+	// if any.
+	OEmbedUrl string `json:",omitempty"`
+	// OEmbed is the HTML returned by the oEmbed endpoint, for oEmbed URLs.
+	OEmbed    string `json:",omitempty"`
+	FetchedAt time.Time
+}
+
+// Cache stores the result of link resolutions on disk, in a JSON file, so that
+// links are only resolved once across runs. It is safe for concurrent use.
+type Cache struct {
+	// TTL is the duration after which entries are considered stale and are
+	// resolved again. A zero TTL means entries never expire.
+	TTL time.Duration
+	// Offline only uses cached results, even stale ones, and never accesses
+	// the network.
+	Offline bool
+
+	path    string
+	mu      sync.Mutex
+	entries map[string]CacheEntry
+	dirty   bool
+	// files are the files of a cache without path, kept in memory
+	files map[string][]byte
+}
+
+// OpenCache loads the cache stored in file path. The file is created on Save
+// if it does not exist yet.
+func OpenCache(path string, ttl time.Duration) (*Cache, error) {
+	cache := Cache{TTL: ttl, path: path, entries: make(map[string]CacheEntry)}
+	data, err := ioutil.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &cache, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entries []CacheEntry
+	if err = json.Unmarshal(data, &entries); err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		cache.entries[e.Url] = e
+	}
+	return &cache, nil
+}
+
+// DefaultCachePath returns the path of the link cache in the user cache
+// directory.
+func DefaultCachePath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "dpk", "links.json"), nil
+}
+
+// Get returns the cached entry for url. Stale entries are only returned in
+// offline mode. A nil cache never has entries.
+func (c *Cache) Get(url string) (CacheEntry, bool) {
+	if c == nil {
+		return CacheEntry{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[url]
+	if !ok {
+		return entry, false
+	}
+	if !c.Offline && c.TTL > 0 && time.Since(entry.FetchedAt) > c.TTL {
+		return entry, false
+	}
+	return entry, true
+}
+
+// Put stores entry in the cache. FetchedAt is set to the current time if it
+// is not set.
+func (c *Cache) Put(entry CacheEntry) {
+	if c == nil {
+		return
+	}
+	if entry.FetchedAt.IsZero() {
+		entry.FetchedAt = time.Now().UTC()
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string]CacheEntry)
+	}
+	c.entries[entry.Url] = entry
+	c.dirty = true
+}
+
+// GetFile returns the content of the file stored for url with PutFile.
+func (c *Cache) GetFile(url string) ([]byte, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.path == "" {
+		data, ok := c.files[url]
+		return data, ok
+	}
+	data, err := ioutil.ReadFile(c.filePath(url))
+	return data, err == nil
+}
+
+// PutFile stores a file downloaded from url, like an image. Files are written
+// right away, in a directory beside the cache file, named after it with a
+// "-files" suffix. They do not expire.
+func (c *Cache) PutFile(url string, data []byte) error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.path == "" {
+		if c.files == nil {
+			c.files = make(map[string][]byte)
+		}
+		c.files[url] = data
+		return nil
+	}
+	file := c.filePath(url)
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, data, 0644)
+}
+
+// filePath returns the path of the file stored for url.
+func (c *Cache) filePath(url string) string {
+	dir := strings.TrimSuffix(c.path, filepath.Ext(c.path)) + "-files"
+	hash := sha256.Sum256([]byte(url))
+	return filepath.Join(dir, hex.EncodeToString(hash[:]))
+}
+
+// IsOffline returns true if the network must not be used.
+func (c *Cache) IsOffline() bool {
+	return c != nil && c.Offline
+}
+
+// Save writes the cache to disk, if it has changed.
+func (c *Cache) Save() error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.dirty {
+		return nil
+	}
+
+	entries := make([]CacheEntry, 0, len(c.entries))
+	for _, e := range c.entries {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Url < entries[j].Url })
+	data, err := json.MarshalIndent(entries, "", "\t")
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return err
+	}
+	// Write to a temporary file first, to not corrupt the cache on failure
+	tmpFile := c.path + ".tmp"
+	if err = ioutil.WriteFile(tmpFile, data, 0644); err != nil {
+		return err
+	}
+	if err = os.Rename(tmpFile, c.path); err != nil {
+		return err
+	}
+	c.dirty = false
+	return nil
+}
@@ -0,0 +1,107 @@
+package semweb_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/processone/dpk/pkg/semweb"
+)
+
+func TestCache(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch r.URL.Path {
+		case "/short":
+			http.Redirect(w, r, "/page", http.StatusMovedPermanently)
+		default:
+			fmt.Fprint(w, "<html><head><title>Page</title></head></html>")
+		}
+	}))
+	defer server.Close()
+
+	cacheFile := filepath.Join(t.TempDir(), "links.json")
+	cache, err := semweb.OpenCache(cacheFile, time.Hour)
+	if err != nil {
+		t.Errorf("Cannot open cache: %s", err)
+		return
+	}
+	client := semweb.NewClient()
+	client.Cache = cache
+
+	expected := server.URL + "/page"
+	if final := client.FollowRedirect(server.URL + "/short"); final != expected {
+		t.Errorf("Incorrect final URL. Got: %s Expected: %s", final, expected)
+	}
+	n := requests
+	if final := client.FollowRedirect(server.URL + "/short"); final != expected || requests != n {
+		t.Errorf("Redirect was not read from cache: %s (%d requests)", final, requests-n)
+	}
+	if err = cache.Save(); err != nil {
+		t.Errorf("Cannot save cache: %s", err)
+		return
+	}
+
+	// Cache is persisted and can be used offline
+	cache, err = semweb.OpenCache(cacheFile, time.Nanosecond)
+	if err != nil {
+		t.Errorf("Cannot reopen cache: %s", err)
+		return
+	}
+	if _, ok := cache.Get(server.URL + "/short"); ok {
+		t.Error("Stale entry should not be returned")
+	}
+	cache.Offline = true
+	client.Cache = cache
+	n = requests
+	if final := client.FollowRedirect(server.URL + "/short"); final != expected || requests != n {
+		t.Errorf("Stale entry should be used offline: %s (%d requests)", final, requests-n)
+	}
+	if final := client.FollowRedirect(server.URL + "/other"); final != server.URL+"/other" || requests != n {
+		t.Errorf("Network should not be used offline: %s (%d requests)", final, requests-n)
+	}
+	if _, err = client.Get(server.URL + "/page"); err == nil || requests != n {
+		t.Error("Page should not be retrieved offline")
+	}
+
+	// Stale entry is used when the link cannot be resolved
+	server.Close()
+	cache.Offline = false
+	if final := client.FollowRedirect(server.URL + "/short"); final != expected {
+		t.Errorf("Stale entry should be used on network errors: %s", final)
+	}
+	if entry, ok := cache.Get(server.URL + "/short"); ok || entry.FinalUrl != expected {
+		t.Errorf("Stale entry should be kept as is: %+v", entry)
+	}
+}
+
+func TestCacheFiles(t *testing.T) {
+	cacheFile := filepath.Join(t.TempDir(), "links.json")
+	cache, err := semweb.OpenCache(cacheFile, time.Hour)
+	if err != nil {
+		t.Errorf("Cannot open cache: %s", err)
+		return
+	}
+	image := "https://example.com/image.jpg"
+	if _, ok := cache.GetFile(image); ok {
+		t.Error("Unexpected file in empty cache")
+	}
+	if err = cache.PutFile(image, []byte("JPEG data")); err != nil {
+		t.Errorf("Cannot store file: %s", err)
+		return
+	}
+
+	// Files are stored on disk right away, and do not expire
+	cache, err = semweb.OpenCache(cacheFile, time.Nanosecond)
+	if err != nil {
+		t.Errorf("Cannot reopen cache: %s", err)
+		return
+	}
+	if data, ok := cache.GetFile(image); !ok || string(data) != "JPEG data" {
+		t.Errorf("Incorrect cached file: %q", data)
+	}
+}
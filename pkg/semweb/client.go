@@ -1,8 +1,12 @@
 package semweb
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"mime"
 	"net"
 	"net/http"
 	"net/url"
@@ -18,6 +22,11 @@ import (
 type Client struct {
 	Client      *http.Client
 	MaxRedirect int
+	// Cache stores the result of redirect resolutions. It is optional.
+	Cache *Cache
+	// PreferCanonical makes resolved links point to the canonical URL of the
+	// target page, when it declares one.
+	PreferCanonical bool
 	// TODO: Support debug logger
 }
 
@@ -38,89 +47,197 @@ func NewClient() Client {
 	return Client{Client: &client, MaxRedirect: 7}
 }
 
+// Errors returned when resolving URLs.
+var (
+	ErrOffline          = errors.New("network access disabled in offline mode")
+	ErrTooManyRedirects = errors.New("maximum number of redirects reached")
+	ErrRedirectLoop     = errors.New("redirect loop")
+	ErrBodyTooLarge     = errors.New("response body too large")
+)
+
+// maxBodySize limits the size of the response bodies read by the client.
+const maxBodySize = 10 << 20
+
+// Hop is a response received while following redirects.
+type Hop struct {
+	Url    string
+	Status int
+	// Location is the Location header of redirect responses, as sent by the
+	// server.
+	Location string `json:",omitempty"`
+	// Duration is the time taken to receive the response headers.
+	Duration time.Duration
+}
+
+// Resolution is the result of following the redirects from a URL.
+type Resolution struct {
+	// Chain lists the responses received, from the requested URL to the
+	// final one.
+	Chain []Hop
+	// FinalUrl is the URL of the last response.
+	FinalUrl string
+	// Status is the HTTP status code of the last response.
+	Status int
+	// Body is the content of the last response. Resolve only reads it for
+	// HTML pages.
+	Body []byte
+	// Page holds the metadata of the last response, for HTML pages.
+	Page Page
+}
+
+// Resolve requests link, following redirects, and returns the redirect chain
+// with the final response. All redirect status codes (301, 302, 303, 307 and
+// 308) are followed. The body of the final response is only read for HTML
+// pages, whose metadata are available in Page. On error, the chain up to the
+// failure is returned.
+func (c Client) Resolve(link string) (Resolution, error) {
+	res, resp, err := c.follow(link)
+	if err != nil {
+		return res, err
+	}
+	defer resp.Body.Close()
+	if !isHtml(resp.Header.Get("Content-Type")) {
+		return res, nil
+	}
+	if res.Body, err = readBody(resp.Body); err != nil {
+		return res, fmt.Errorf("%s: %w", res.FinalUrl, err)
+	}
+	// Metadata are optional: pages that cannot be parsed have none
+	res.Page, _ = ReadPage(bytes.NewReader(res.Body))
+	return res, nil
+}
+
+// Fetch requests link, following redirects as Resolve does, and returns the
+// redirect chain with the body of the final response, whatever its content
+// type.
+func (c Client) Fetch(link string) (Resolution, error) {
+	res, resp, err := c.follow(link)
+	if err != nil {
+		return res, err
+	}
+	defer resp.Body.Close()
+	if res.Body, err = readBody(resp.Body); err != nil {
+		return res, fmt.Errorf("%s: %w", res.FinalUrl, err)
+	}
+	return res, nil
+}
+
 // Get returns a web page reader, following a predefined number of redirects.
 func (c Client) Get(url string) (io.ReadCloser, error) {
+	res, resp, err := c.follow(url)
+	if err != nil {
+		return nil, err
+	}
+	if res.Status != 200 {
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("unexpected response code %d", res.Status)
+	}
+	return resp.Body, nil
+}
+
+// follow requests link and follows redirects. It returns the redirect chain,
+// with the final response, whose body must be closed by the caller.
+func (c Client) follow(link string) (Resolution, *http.Response, error) {
+	var res Resolution
+	if c.Cache.IsOffline() {
+		return res, nil, fmt.Errorf("cannot get %s: %w", link, ErrOffline)
+	}
+	visited := make(map[string]bool)
 	for redirect := 0; redirect <= c.MaxRedirect; redirect++ {
-		resp, err := c.Client.Get(url)
+		if visited[link] {
+			return res, nil, fmt.Errorf("%w: %s", ErrRedirectLoop, link)
+		}
+		visited[link] = true
+
+		start := time.Now()
+		resp, err := c.Client.Get(link)
 		if err != nil {
-			return nil, err
+			return res, nil, err
 		}
+		hop := Hop{Url: link, Status: resp.StatusCode, Duration: time.Since(start)}
+		res.FinalUrl = link
+		res.Status = resp.StatusCode
 
-		switch {
-		case resp.StatusCode >= 300 && resp.StatusCode < 400:
-			// Redirect
-			location := resp.Header.Get("Location")
-			// Retry resolving the next link, with new discovered location
-			url, err = formatRedirectUrl(url, location)
-			_ = resp.Body.Close()
-			if err != nil {
-				// Not a valid URL, do not redirect further
-				return nil, err
-			}
-			// TODO: Display using debug or verbose option
-			// fmt.Println("=> Resolved as", location)
-		case resp.StatusCode == 200:
-			// Success
-			return resp.Body, nil
-		default:
-			_ = resp.Body.Close()
-			return nil, fmt.Errorf("unexpected response code %d", resp.StatusCode)
+		if !isRedirect(resp.StatusCode) {
+			res.Chain = append(res.Chain, hop)
+			return res, resp, nil
 		}
 
+		_ = resp.Body.Close()
+		hop.Location = resp.Header.Get("Location")
+		res.Chain = append(res.Chain, hop)
+		// Retry resolving the next link, with new discovered location
+		if link, err = RedirectUrl(link, hop.Location); err != nil {
+			return res, nil, err
+		}
 	}
-	return nil, fmt.Errorf("maximum number of redirects reached")
+	return res, nil, ErrTooManyRedirects
 }
 
-// Follow redirect and return final URL
-func (c Client) FollowRedirect(currentUrl string) string {
-Loop:
-	// Try to resolve link N times, as sometimes you can find a chain of redirects before
-	// reaching the canonical link.
-	for redirect := 0; redirect <= c.MaxRedirect; redirect++ {
-		resp, err := c.Client.Get(currentUrl)
-		if err != nil {
-			fmt.Println(err)
-			return currentUrl
+// ResolveLink returns the final URL of link, with the metadata of the target
+// page: title, description, image, canonical URL and oEmbed URL. When the
+// client has a cache, the result is read from and stored in the cache. If link
+// cannot be resolved, its expired cache entry is returned, when available.
+func (c Client) ResolveLink(link string) (CacheEntry, error) {
+	cached, ok := c.Cache.Get(link)
+	if ok && cached.FinalUrl != "" {
+		return cached, nil
+	}
+	res, err := c.Resolve(link)
+	// The final URL of pages too large to be read is still known
+	if err != nil && !errors.Is(err, ErrBodyTooLarge) {
+		if cached.FinalUrl != "" {
+			// The expired entry is kept, to be refreshed by a later run
+			return cached, nil
 		}
-
-		switch resp.StatusCode {
-		case 301, 302:
-			locationHdr := resp.Header.Get("Location")
-			// Retry resolving the next link, with new discovered location
-			link, err := formatRedirectUrl(currentUrl, locationHdr)
-			if err != nil {
-				// Not a valid URL, just return the original link as is
-				_ = resp.Body.Close()
-				break Loop
-			}
-			// TODO: Display using debug or verbose option
-			// fmt.Println("=> Resolved as", link)
-
-			_, err = url.Parse(link)
-			if err != nil {
-				// Not a valid URL, just return the original link as is
-				_ = resp.Body.Close()
-				break Loop
-			}
-			// Retry resolving the next link, with new discovered value
-			currentUrl = locationHdr
-		case 200:
-			/* TODO: Refactor this in a method to get the page  body + final URL in a same call
-			page, err := metadata.ReadPage(resp.Body)
-			if err == nil {
-				displayUrl = page.Title()
-			}
-			*/
-			resp.Body.Close()
-			break Loop
-		default:
-			fmt.Println("Ignored HTTP Status Code:", resp.StatusCode)
-			resp.Body.Close()
-			break Loop
+		return CacheEntry{}, err
+	}
+	entry := CacheEntry{Url: link, FinalUrl: res.FinalUrl, Status: res.Status}
+	if res.Status == 200 {
+		entry.Title = res.Page.Title()
+		entry.Description = res.Page.Description()
+		entry.SiteName = res.Page.SiteName()
+		if image := res.Page.Image(); image != "" {
+			entry.Image, _ = RedirectUrl(res.FinalUrl, image)
+		}
+		if canonical := res.Page.Canonical(); canonical != "" {
+			entry.Canonical, _ = RedirectUrl(res.FinalUrl, canonical)
 		}
+		if oembed := res.Page.OEmbedUrl(); oembed != "" {
+			entry.OEmbedUrl, _ = RedirectUrl(res.FinalUrl, oembed)
+		}
+	}
+	c.Cache.Put(entry)
+	if res.FinalUrl != link {
+		// The final URL resolves to itself
+		final := entry
+		final.Url = res.FinalUrl
+		c.Cache.Put(final)
 	}
+	return entry, nil
+}
 
-	return currentUrl
+// LinkTarget returns the URL a resolved link should point to: its final URL,
+// or the canonical URL of the page if preferred, without tracking parameters.
+func (c Client) LinkTarget(entry CacheEntry) string {
+	target := entry.FinalUrl
+	if c.PreferCanonical && entry.Canonical != "" {
+		target = entry.Canonical
+	}
+	return CleanUrl(target)
+}
+
+// Follow redirect and return final URL, without tracking parameters. When the
+// client has a cache, the final URL is read from and stored in the cache.
+func (c Client) FollowRedirect(link string) string {
+	entry, err := c.ResolveLink(link)
+	if err != nil {
+		if !errors.Is(err, ErrOffline) {
+			fmt.Println(err)
+		}
+		return CleanUrl(link)
+	}
+	return c.LinkTarget(entry)
 }
 
 // TODO: Should this method be on Context, taking only new link ?
@@ -140,22 +257,45 @@ func (c Client) ResolveReference(base, href string) string {
 //=============================================================================
 // HTTP request helpers
 
-// formatRedirectUrl returns a valid full URL from an original URL and a "Location" Header.
-// It support local redirection on same host.
-func formatRedirectUrl(originalUrl, locationHeader string) (string, error) {
-	newUrl, err := url.Parse(locationHeader)
+// RedirectUrl returns a valid full URL from an original URL and a "Location"
+// header. It supports relative redirections.
+func RedirectUrl(originalUrl, locationHeader string) (string, error) {
+	location, err := url.Parse(locationHeader)
 	if err != nil {
 		return "", err
 	}
+	base, err := url.Parse(originalUrl)
+	if err != nil {
+		return "", err
+	}
+	return base.ResolveReference(location).String(), nil
+}
 
-	// This is a relative URL, we need to use the host from original URL
-	if newUrl.Host == "" && newUrl.Scheme == "" {
-		oldUrl, err := url.Parse(originalUrl)
-		if err != nil {
-			return "", err
-		}
-		newUrl.Host = oldUrl.Host
-		newUrl.Scheme = oldUrl.Scheme
+// readBody reads a response body, up to maxBodySize.
+func readBody(body io.Reader) ([]byte, error) {
+	data, err := ioutil.ReadAll(io.LimitReader(body, maxBodySize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxBodySize {
+		return nil, ErrBodyTooLarge
+	}
+	return data, nil
+}
+
+// isHtml returns true if contentType is the media type of an HTML page.
+func isHtml(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "text/html" || mediaType == "application/xhtml+xml"
+}
+
+func isRedirect(status int) bool {
+	switch status {
+	case 301, 302, 303, 307, 308:
+		return true
 	}
-	return newUrl.String(), nil
+	return false
 }
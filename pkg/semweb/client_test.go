@@ -0,0 +1,151 @@
+package semweb_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/processone/dpk/pkg/semweb"
+)
+
+func TestResolve(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/short":
+			http.Redirect(w, r, "/see-other", http.StatusMovedPermanently)
+		case "/see-other":
+			// Relative to the current path
+			w.Header().Set("Location", "temporary")
+			w.WriteHeader(http.StatusSeeOther)
+		case "/temporary":
+			http.Redirect(w, r, "/permanent?q=1", http.StatusTemporaryRedirect)
+		case "/permanent":
+			http.Redirect(w, r, "/page", http.StatusPermanentRedirect)
+		case "/loop":
+			http.Redirect(w, r, "/loop2", http.StatusFound)
+		case "/loop2":
+			http.Redirect(w, r, "/loop", http.StatusFound)
+		default:
+			fmt.Fprint(w, `<html><head><title>Page</title></head><body>Content</body></html>`)
+		}
+	}))
+	defer server.Close()
+
+	client := semweb.NewClient()
+	res, err := client.Resolve(server.URL + "/short")
+	if err != nil {
+		t.Errorf("Cannot resolve URL: %s", err)
+		return
+	}
+	expected := []semweb.Hop{
+		{Url: server.URL + "/short", Status: 301, Location: "/see-other"},
+		{Url: server.URL + "/see-other", Status: 303, Location: "temporary"},
+		{Url: server.URL + "/temporary", Status: 307, Location: "/permanent?q=1"},
+		{Url: server.URL + "/permanent?q=1", Status: 308, Location: "/page"},
+		{Url: server.URL + "/page", Status: 200},
+	}
+	for i, hop := range res.Chain {
+		if hop.Duration <= 0 {
+			t.Errorf("Missing duration for %s", hop.Url)
+		}
+		res.Chain[i].Duration = 0
+	}
+	if !reflect.DeepEqual(res.Chain, expected) {
+		t.Errorf("Incorrect redirect chain: %+v", res.Chain)
+	}
+	if res.FinalUrl != server.URL+"/page" || res.Status != 200 {
+		t.Errorf("Incorrect final URL: %s (%d)", res.FinalUrl, res.Status)
+	}
+	if res.Page.Title() != "Page" {
+		t.Errorf("Incorrect page title: %q", res.Page.Title())
+	}
+	if len(res.Body) == 0 {
+		t.Error("Missing page body")
+	}
+
+	if _, err = client.Resolve(server.URL + "/loop"); !errors.Is(err, semweb.ErrRedirectLoop) {
+		t.Errorf("Redirect loop not detected: %v", err)
+	}
+}
+
+func TestResolveBody(t *testing.T) {
+	image := []byte("\x89PNG\r\n\x1a\n")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/image.png":
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write(image)
+		case "/large":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			_, _ = w.Write(bytes.Repeat([]byte(" "), 10<<20+1))
+		}
+	}))
+	defer server.Close()
+
+	// Only the body of HTML pages is read by Resolve
+	client := semweb.NewClient()
+	res, err := client.Resolve(server.URL + "/image.png")
+	if err != nil || res.Status != 200 || res.Body != nil {
+		t.Errorf("Body of non HTML response should not be read: %d %q %v", res.Status, res.Body, err)
+	}
+	res, err = client.Fetch(server.URL + "/image.png")
+	if err != nil || !bytes.Equal(res.Body, image) {
+		t.Errorf("Incorrect fetched body: %q %v", res.Body, err)
+	}
+
+	// Large bodies are not truncated
+	if _, err = client.Fetch(server.URL + "/large"); !errors.Is(err, semweb.ErrBodyTooLarge) {
+		t.Errorf("Large body not detected: %v", err)
+	}
+	entry, err := client.ResolveLink(server.URL + "/large")
+	if err != nil || entry.FinalUrl != server.URL+"/large" {
+		t.Errorf("Page too large should still be resolved: %+v %v", entry, err)
+	}
+}
+
+func TestRedirectUrl(t *testing.T) {
+	tests := []struct {
+		location string
+		expected string
+	}{
+		{"/en-US/index.html", "https://donate.mozilla.org/en-US/index.html"},
+		{"index.html", "https://donate.mozilla.org/fr/index.html"},
+		{"//mozilla.org/", "https://mozilla.org/"},
+		{"http://example.com/page", "http://example.com/page"},
+	}
+	for _, test := range tests {
+		newUrl, err := semweb.RedirectUrl("https://donate.mozilla.org/fr/", test.location)
+		if err != nil {
+			t.Errorf("Could not properly generate full redirect URL: %s", err)
+			continue
+		}
+		if newUrl != test.expected {
+			t.Errorf("Incorrect redirect URL. Got: '%s' Expected: '%s'", newUrl, test.expected)
+		}
+	}
+}
+
+func TestFollowRedirectCanonical(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/short":
+			http.Redirect(w, r, "/article?id=1&utm_source=twitter", http.StatusMovedPermanently)
+		default:
+			fmt.Fprint(w, `<html><head><link rel="canonical" href="/articles/1"></head></html>`)
+		}
+	}))
+	defer server.Close()
+
+	client := semweb.NewClient()
+	if final := client.FollowRedirect(server.URL + "/short"); final != server.URL+"/article?id=1" {
+		t.Errorf("Tracking parameters not removed: %s", final)
+	}
+	client.PreferCanonical = true
+	if final := client.FollowRedirect(server.URL + "/short"); final != server.URL+"/articles/1" {
+		t.Errorf("Canonical URL not used: %s", final)
+	}
+}
@@ -32,6 +32,45 @@ func (p Page) Title() string {
 	return ""
 }
 
+// Description returns the page description based on defined priorities (og >
+// twitter > description)
+func (p Page) Description() string {
+	return p.firstProperty("og:description", "twitter:description", "description")
+}
+
+// SiteName returns the name of the site the page belongs to, if declared.
+func (p Page) SiteName() string {
+	return p.Properties["og:site_name"]
+}
+
+// Image returns the URL of the image representing the page, if declared. It
+// can be relative to the page URL.
+func (p Page) Image() string {
+	return p.firstProperty("og:image", "twitter:image")
+}
+
+func (p Page) firstProperty(names ...string) string {
+	for _, name := range names {
+		if value := p.Properties[name]; value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// Canonical returns the canonical URL of the page, as declared with a link
+// element, or an empty string. It can be relative to the page URL.
+func (p Page) Canonical() string {
+	return p.Properties["canonical"]
+}
+
+// OEmbedUrl returns the URL of the JSON oEmbed representation of the page, as
+// declared with a link element, or an empty string. It can be relative to the
+// page URL.
+func (p Page) OEmbedUrl() string {
+	return p.Properties["oembed"]
+}
+
 // ReadPage is used to extract metadata from an HTML page.
 // It returns a Page struct for easy manipulation of those metadata.
 func ReadPage(body io.Reader) (Page, error) {
@@ -59,6 +98,13 @@ Loop:
 				if contains(knownProperties, meta.property) {
 					p.Properties[meta.property] = meta.content
 				}
+			case "link":
+				if href, matched := matchAttr(token, "rel", "canonical", "href"); matched {
+					p.Properties["canonical"] = href
+				}
+				if href, matched := matchAttr(token, "type", "application/json+oembed", "href"); matched {
+					p.Properties["oembed"] = href
+				}
 			case "title":
 				// The next token should be the page title
 				tokenType = tokenizer.Next()
@@ -104,7 +150,7 @@ Loop:
 				relUrl, matched := matchAttr(token, "rel", "me", "href")
 				if matched {
 					absoluteUrl := ctx.Client.ResolveReference(ctx.Url, relUrl)
-					urls = append(urls, absoluteUrl)
+					urls = append(urls, CleanUrl(absoluteUrl))
 				}
 			}
 		}
@@ -196,8 +242,5 @@ func contains(array []string, str string) bool {
 // - Contains example for XHTML and for setting metadata outside of HTML head
 //   https://www.w3.org/MarkUp/2009/rdfa-for-html-authors
 
-// TODO also extract og:image. e.g.:
-// <meta property="og:image" content="https://gigaom.com/wp-content/uploads/sites/1/2011/01/sonosgroup-804x516.jpg" />
-
 // TODO Add support for older Dublin Core syntax.
 // See: https://www.slideshare.net/eduservfoundation/dublin-core-basic-syntax-tutorial
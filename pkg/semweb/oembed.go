@@ -0,0 +1,147 @@
+package semweb
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+//=============================================================================
+// oEmbed
+// Find the oEmbed endpoint providing the embeddable representation of a URL.
+// See: https://oembed.com/
+
+// OEmbed is the response of an oEmbed endpoint.
+type OEmbed struct {
+	EmbedType    string `json:"type"`
+	URL          string
+	Title        string
+	AuthorName   string `json:"author_name"`
+	AuthorURL    string `json:"author_url"`
+	HTML         string
+	Width        int
+	Height       int
+	CacheAge     string `json:"cache_age"`
+	ProviderName string `json:"provider_name"`
+	ProviderURL  string `json:"provider_url"`
+	Version      string
+}
+
+// OEmbedProvider is a service providing oEmbed representations of the URLs
+// matching its schemes.
+type OEmbedProvider struct {
+	Name string
+	// Schemes are the URL patterns supported by the provider, where "*"
+	// matches any string. URLs are matched regardless of their http or https
+	// scheme.
+	Schemes  []string
+	Endpoint string
+
+	patterns []*regexp.Regexp
+}
+
+// OEmbedProviders is the registry of known oEmbed providers, used before
+// looking for an oEmbed endpoint in the page.
+var OEmbedProviders = []OEmbedProvider{
+	{
+		Name:     "Twitter",
+		Schemes:  []string{"https://twitter.com/*"},
+		Endpoint: "https://publish.twitter.com/oembed",
+	},
+	{
+		Name:     "Vimeo",
+		Schemes:  []string{"https://vimeo.com/*", "https://player.vimeo.com/video/*"},
+		Endpoint: "https://vimeo.com/api/oembed.json",
+	},
+	{
+		Name:     "SoundCloud",
+		Schemes:  []string{"https://soundcloud.com/*"},
+		Endpoint: "https://soundcloud.com/oembed?format=json",
+	},
+	{
+		Name:     "Flickr",
+		Schemes:  []string{"https://*.flickr.com/photos/*", "https://flic.kr/p/*"},
+		Endpoint: "https://www.flickr.com/services/oembed/?format=json",
+	},
+	{
+		Name:     "Dailymotion",
+		Schemes:  []string{"https://www.dailymotion.com/video/*", "https://dai.ly/*"},
+		Endpoint: "https://www.dailymotion.com/services/oembed",
+	},
+	{
+		Name:     "Spotify",
+		Schemes:  []string{"https://open.spotify.com/*"},
+		Endpoint: "https://open.spotify.com/oembed",
+	},
+	{
+		Name:     "SlideShare",
+		Schemes:  []string{"https://www.slideshare.net/*/*"},
+		Endpoint: "https://www.slideshare.net/api/oembed/2?format=json",
+	},
+	{
+		Name:     "Speaker Deck",
+		Schemes:  []string{"https://speakerdeck.com/*/*"},
+		Endpoint: "https://speakerdeck.com/oembed.json",
+	},
+	{
+		Name:     "Mixcloud",
+		Schemes:  []string{"https://www.mixcloud.com/*/*"},
+		Endpoint: "https://www.mixcloud.com/oembed/?format=json",
+	},
+}
+
+func init() {
+	for i := range OEmbedProviders {
+		OEmbedProviders[i].compile()
+	}
+}
+
+// compile converts the provider schemes to regular expressions. In the host,
+// "*" only matches subdomains.
+func (p *OEmbedProvider) compile() {
+	p.patterns = nil
+	for _, scheme := range p.Schemes {
+		rest := scheme[strings.Index(scheme, "://")+3:]
+		host, path := rest, ""
+		if i := strings.IndexByte(rest, '/'); i >= 0 {
+			host, path = rest[:i], rest[i:]
+		}
+		pattern := `^https?://` +
+			strings.ReplaceAll(regexp.QuoteMeta(host), `\*`, `[^/]*`) +
+			strings.ReplaceAll(regexp.QuoteMeta(path), `\*`, `.*`) + `$`
+		p.patterns = append(p.patterns, regexp.MustCompile(pattern))
+	}
+}
+
+// Matches returns true if the provider supports link.
+func (p OEmbedProvider) Matches(link string) bool {
+	if p.patterns == nil {
+		p.compile()
+	}
+	for _, pattern := range p.patterns {
+		if pattern.MatchString(link) {
+			return true
+		}
+	}
+	return false
+}
+
+// OEmbedUrl returns the URL of the oEmbed representation of link, from the
+// registry of known providers, or an empty string if no provider supports
+// link.
+func OEmbedUrl(link string) string {
+	for _, provider := range OEmbedProviders {
+		if !provider.Matches(link) {
+			continue
+		}
+		endpoint, err := url.Parse(provider.Endpoint)
+		if err != nil {
+			return ""
+		}
+		query := endpoint.Query()
+		query.Set("url", link)
+		endpoint.RawQuery = query.Encode()
+		return endpoint.String()
+	}
+	return ""
+}
@@ -0,0 +1,50 @@
+package semweb_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/processone/dpk/pkg/semweb"
+)
+
+func TestOEmbedUrl(t *testing.T) {
+	tests := []struct {
+		link     string
+		expected string
+	}{
+		{"https://vimeo.com/76979871", "https://vimeo.com/api/oembed.json?url=https%3A%2F%2Fvimeo.com%2F76979871"},
+		{"http://vimeo.com/76979871", "https://vimeo.com/api/oembed.json?url=http%3A%2F%2Fvimeo.com%2F76979871"},
+		{"https://www.flickr.com/photos/bees/2341623661/", "https://www.flickr.com/services/oembed/?format=json&url=https%3A%2F%2Fwww.flickr.com%2Fphotos%2Fbees%2F2341623661%2F"},
+		{"https://twitter.com/processone/status/1", "https://publish.twitter.com/oembed?url=https%3A%2F%2Ftwitter.com%2Fprocessone%2Fstatus%2F1"},
+		// Wildcards in host do not match paths
+		{"https://example.com/a.flickr.com/photos/bees/1", ""},
+		{"https://www.process-one.net/", ""},
+	}
+	for _, test := range tests {
+		if endpoint := semweb.OEmbedUrl(test.link); endpoint != test.expected {
+			t.Errorf("Incorrect oEmbed URL for %s. Got: '%s' Expected: '%s'", test.link, endpoint, test.expected)
+		}
+	}
+}
+
+func TestOEmbedDiscovery(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><head>
+<title>Talk</title>
+<link rel="alternate" type="application/json+oembed" href="/oembed?url=%2Ftalk&amp;format=json" title="Talk">
+</head></html>`)
+	}))
+	defer server.Close()
+
+	client := semweb.NewClient()
+	entry, err := client.ResolveLink(server.URL + "/talk")
+	if err != nil {
+		t.Errorf("Cannot resolve link: %s", err)
+		return
+	}
+	if expected := server.URL + "/oembed?url=%2Ftalk&format=json"; entry.OEmbedUrl != expected {
+		t.Errorf("Incorrect discovered oEmbed URL. Got: '%s' Expected: '%s'", entry.OEmbedUrl, expected)
+	}
+}
@@ -0,0 +1,128 @@
+package semweb
+
+import (
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+//=============================================================================
+// Worker pool
+// Process URLs concurrently, without overloading the hosts they point to
+
+// Pool processes a set of URLs with a bounded number of workers. Requests to
+// a single host are limited in number and spaced in time, so that services
+// like link shorteners do not throttle us.
+type Pool struct {
+	// Workers is the maximum number of URLs processed at the same time.
+	Workers int
+	// HostConcurrency is the maximum number of URLs of the same host processed
+	// at the same time.
+	HostConcurrency int
+	// HostInterval is the minimum duration between the start of the
+	// processing of two URLs of the same host.
+	HostInterval time.Duration
+}
+
+// hostLimit tracks the URLs of a host being processed.
+type hostLimit struct {
+	slots chan struct{}
+	next  time.Time
+}
+
+// Run calls fn for each URL of urls, concurrently, and returns when all URLs
+// have been processed. Duplicate URLs are only processed once. fn must be
+// safe for concurrent use.
+func (p Pool) Run(urls []string, fn func(url string)) {
+	workers := p.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	perHost := p.HostConcurrency
+	if perHost < 1 {
+		perHost = 1
+	}
+
+	var mu sync.Mutex
+	hosts := make(map[string]*hostLimit)
+	// acquire waits until the URL host can be accessed, and returns a function
+	// to call when done.
+	acquire := func(host string) func() {
+		mu.Lock()
+		h, ok := hosts[host]
+		if !ok {
+			h = &hostLimit{slots: make(chan struct{}, perHost)}
+			hosts[host] = h
+		}
+		mu.Unlock()
+
+		h.slots <- struct{}{}
+		mu.Lock()
+		now := time.Now()
+		start := h.next
+		if start.Before(now) {
+			start = now
+		}
+		h.next = start.Add(p.HostInterval)
+		mu.Unlock()
+		time.Sleep(start.Sub(now))
+		return func() { <-h.slots }
+	}
+
+	jobs := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for u := range jobs {
+				release := acquire(urlHost(u))
+				fn(u)
+				release()
+			}
+		}()
+	}
+	for _, u := range interleaveHosts(urls) {
+		jobs <- u
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// interleaveHosts removes duplicate URLs and orders them so that URLs of the
+// same host are spread, to avoid having all workers waiting for the same host.
+func interleaveHosts(urls []string) []string {
+	var hosts []string
+	byHost := make(map[string][]string)
+	seen := make(map[string]bool)
+	for _, u := range urls {
+		if seen[u] {
+			continue
+		}
+		seen[u] = true
+		host := urlHost(u)
+		if _, ok := byHost[host]; !ok {
+			hosts = append(hosts, host)
+		}
+		byHost[host] = append(byHost[host], u)
+	}
+
+	ordered := make([]string, 0, len(seen))
+	for i := 0; len(ordered) < len(seen); i++ {
+		for _, host := range hosts {
+			if i < len(byHost[host]) {
+				ordered = append(ordered, byHost[host][i])
+			}
+		}
+	}
+	return ordered
+}
+
+func urlHost(link string) string {
+	u, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Host)
+}
@@ -0,0 +1,65 @@
+package semweb_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/processone/dpk/pkg/semweb"
+)
+
+func TestPool(t *testing.T) {
+	var urls []string
+	for _, host := range []string{"bit.ly", "t.co", "example.com"} {
+		for _, path := range []string{"/a", "/b", "/c", "/d"} {
+			urls = append(urls, "https://"+host+path)
+		}
+	}
+	// Duplicates are only processed once
+	urls = append(urls, urls[0], urls[5])
+
+	var mu sync.Mutex
+	processed := make(map[string]int)
+	active := make(map[string]int)
+	maxActive := make(map[string]int)
+	starts := make(map[string][]time.Time)
+	pool := semweb.Pool{Workers: 6, HostConcurrency: 2, HostInterval: 10 * time.Millisecond}
+	pool.Run(urls, func(u string) {
+		host := u[len("https://") : len(u)-2]
+		mu.Lock()
+		processed[u]++
+		active[host]++
+		if active[host] > maxActive[host] {
+			maxActive[host] = active[host]
+		}
+		starts[host] = append(starts[host], time.Now())
+		mu.Unlock()
+
+		time.Sleep(30 * time.Millisecond)
+
+		mu.Lock()
+		active[host]--
+		mu.Unlock()
+	})
+
+	if len(processed) != 12 {
+		t.Errorf("Unexpected number of processed URLs: %d", len(processed))
+	}
+	for u, count := range processed {
+		if count != 1 {
+			t.Errorf("%s processed %d times", u, count)
+		}
+	}
+	for host, max := range maxActive {
+		if max > 2 {
+			t.Errorf("%s: %d concurrent requests, expected at most 2", host, max)
+		}
+	}
+	for host, times := range starts {
+		// Requests to a host are spaced by 10ms: the 4 requests span at least
+		// 30ms, allowing for timer imprecision
+		if d := times[len(times)-1].Sub(times[0]); d < 27*time.Millisecond {
+			t.Errorf("%s: %d requests started within %s", host, len(times), d)
+		}
+	}
+}
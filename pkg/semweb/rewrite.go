@@ -0,0 +1,171 @@
+package semweb
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+//=============================================================================
+// URL rewrite rules
+// Fix links to dead or mobile domains, without accessing the network
+
+// RewriteRule rewrites the URLs matching its host and path. A rule performs
+// one action: extracting the target URL from a query parameter, replacing the
+// host, or replacing the URL using a regular expression.
+type RewriteRule struct {
+	// Host is the host of the URLs to rewrite. "*.example.com" matches all the
+	// subdomains of example.com. An empty host matches all hosts.
+	Host string `yaml:"host,omitempty" json:"host,omitempty"`
+	// Path is a regular expression matching the path of the URLs to rewrite.
+	Path string `yaml:"path,omitempty" json:"path,omitempty"`
+
+	// Param is the query parameter holding the target URL.
+	Param string `yaml:"param,omitempty" json:"param,omitempty"`
+	// NewHost replaces the host of the URL.
+	NewHost string `yaml:"new_host,omitempty" json:"new_host,omitempty"`
+	// Pattern is a regular expression matching the whole URL, replaced with
+	// Replace, which can refer to submatches as $1.
+	Pattern string `yaml:"pattern,omitempty" json:"pattern,omitempty"`
+	Replace string `yaml:"replace,omitempty" json:"replace,omitempty"`
+
+	path    *regexp.Regexp
+	pattern *regexp.Regexp
+}
+
+// DefaultRewriteRules fix common cases: links to mobile sites, Google AMP
+// pages, and redirection pages of sites that do not exist anymore.
+var DefaultRewriteRules = []RewriteRule{
+	// Engadget mobile site is gone, but article URLs are in the artUrl parameter
+	{Host: "m.engadget.com", Path: `^/default/article\.do$`, Param: "artUrl"},
+	// Google AMP cache and viewer
+	{Pattern: `^https?://(?:www\.)?google\.[a-z.]+/amp/s/(.+)$`, Replace: "https://$1"},
+	{Pattern: `^https?://(?:www\.)?google\.[a-z.]+/amp/(.+)$`, Replace: "http://$1"},
+	{Pattern: `^https?://[^/]+\.cdn\.ampproject\.org/[a-z]/s/(.+)$`, Replace: "https://$1"},
+	// Mobile sites
+	{Host: "mobile.twitter.com", NewHost: "twitter.com"},
+	{Host: "m.facebook.com", NewHost: "www.facebook.com"},
+	{Host: "m.youtube.com", NewHost: "www.youtube.com"},
+	{Pattern: `^(https?)://([a-z-]+)\.m\.wikipedia\.org/(.*)$`, Replace: "$1://$2.wikipedia.org/$3"},
+}
+
+// maxRewrites limits the number of rules applied to a URL, as the result of a
+// rule can itself be rewritten.
+const maxRewrites = 5
+
+// Rewriter applies rewrite rules to URLs.
+type Rewriter struct {
+	rules []RewriteRule
+}
+
+// NewRewriter returns a rewriter applying rules, in order.
+func NewRewriter(rules []RewriteRule) (*Rewriter, error) {
+	r := Rewriter{rules: make([]RewriteRule, len(rules))}
+	for i, rule := range rules {
+		if rule.Param == "" && rule.NewHost == "" && rule.Pattern == "" {
+			return nil, fmt.Errorf("rewrite rule %d has no action", i+1)
+		}
+		var err error
+		if rule.Path != "" {
+			if rule.path, err = regexp.Compile(rule.Path); err != nil {
+				return nil, fmt.Errorf("rewrite rule %d: %w", i+1, err)
+			}
+		}
+		if rule.Pattern != "" {
+			if rule.pattern, err = regexp.Compile(rule.Pattern); err != nil {
+				return nil, fmt.Errorf("rewrite rule %d: %w", i+1, err)
+			}
+		}
+		r.rules[i] = rule
+	}
+	return &r, nil
+}
+
+// DefaultRewriter returns a rewriter applying DefaultRewriteRules.
+func DefaultRewriter() *Rewriter {
+	r, err := NewRewriter(DefaultRewriteRules)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// LoadRewriter returns a rewriter applying the rules of file path, a YAML or
+// JSON list of rules, before the default rules.
+func LoadRewriter(path string) (*Rewriter, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rules []RewriteRule
+	// JSON is valid YAML
+	if err = yaml.UnmarshalStrict(data, &rules); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return NewRewriter(append(rules, DefaultRewriteRules...))
+}
+
+// Rewrite returns link rewritten by the first matching rule. The result is
+// rewritten again, as it can also match a rule. A nil rewriter returns link
+// unchanged.
+func (r *Rewriter) Rewrite(link string) string {
+	if r == nil {
+		return link
+	}
+	for i := 0; i < maxRewrites; i++ {
+		rewritten, ok := r.rewriteOnce(link)
+		if !ok || rewritten == link {
+			break
+		}
+		link = rewritten
+	}
+	return link
+}
+
+func (r *Rewriter) rewriteOnce(link string) (string, bool) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return link, false
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, rule := range r.rules {
+		if !rule.matches(host, u.Path) {
+			continue
+		}
+		switch {
+		case rule.Param != "":
+			target := u.Query().Get(rule.Param)
+			if t, err := url.Parse(target); err != nil || !t.IsAbs() {
+				continue
+			}
+			return target, true
+		case rule.NewHost != "":
+			rewritten := *u
+			rewritten.Host = rule.NewHost
+			if port := u.Port(); port != "" {
+				rewritten.Host += ":" + port
+			}
+			return rewritten.String(), true
+		case rule.pattern.MatchString(link):
+			return rule.pattern.ReplaceAllString(link, rule.Replace), true
+		}
+	}
+	return link, false
+}
+
+func (rule RewriteRule) matches(host, path string) bool {
+	switch {
+	case rule.Host == "":
+	case strings.HasPrefix(rule.Host, "*."):
+		if !strings.HasSuffix(host, rule.Host[1:]) {
+			return false
+		}
+	case !strings.EqualFold(host, rule.Host):
+		return false
+	}
+	return rule.path == nil || rule.path.MatchString(path)
+}
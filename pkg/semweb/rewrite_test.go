@@ -0,0 +1,78 @@
+package semweb_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/processone/dpk/pkg/semweb"
+)
+
+func TestRewriter(t *testing.T) {
+	tests := []struct {
+		link     string
+		expected string
+	}{
+		{
+			"http://m.engadget.com/default/article.do?artUrl=http://www.engadget.com/2011/02/08/nokia-ceo-stephen-elop-rallies-troops-in-brutally-honest-burnin/&category=classic&postPage=1",
+			"http://www.engadget.com/2011/02/08/nokia-ceo-stephen-elop-rallies-troops-in-brutally-honest-burnin/",
+		},
+		{"https://www.google.com/amp/s/www.example.com/news/article.amp.html", "https://www.example.com/news/article.amp.html"},
+		{"https://www-example-com.cdn.ampproject.org/c/s/www.example.com/article", "https://www.example.com/article"},
+		{"https://mobile.twitter.com/processone/status/1", "https://twitter.com/processone/status/1"},
+		{"https://fr.m.wikipedia.org/wiki/XMPP", "https://fr.wikipedia.org/wiki/XMPP"},
+		// Rules apply to the result of other rules
+		{"https://www.google.com/amp/s/m.youtube.com/watch?v=abc", "https://www.youtube.com/watch?v=abc"},
+		{"https://www.example.com/page", "https://www.example.com/page"},
+	}
+	rewriter := semweb.DefaultRewriter()
+	for _, test := range tests {
+		if rewritten := rewriter.Rewrite(test.link); rewritten != test.expected {
+			t.Errorf("Incorrect rewrite for %s. Got: '%s' Expected: '%s'", test.link, rewritten, test.expected)
+		}
+	}
+}
+
+func TestLoadRewriter(t *testing.T) {
+	rulesFile := filepath.Join(t.TempDir(), "rules.yaml")
+	rules := `
+- host: "*.example.org"
+  path: ^/redirect
+  param: to
+- host: old.example.com
+  new_host: www.example.com
+`
+	if err := ioutil.WriteFile(rulesFile, []byte(rules), 0644); err != nil {
+		t.Errorf("Cannot write rules: %s", err)
+		return
+	}
+	rewriter, err := semweb.LoadRewriter(rulesFile)
+	if err != nil {
+		t.Errorf("Cannot load rules: %s", err)
+		return
+	}
+
+	tests := []struct {
+		link     string
+		expected string
+	}{
+		{"https://go.example.org/redirect?to=https%3A%2F%2Fprocess-one.net%2F", "https://process-one.net/"},
+		{"https://go.example.org/other?to=https%3A%2F%2Fprocess-one.net%2F", "https://go.example.org/other?to=https%3A%2F%2Fprocess-one.net%2F"},
+		{"http://old.example.com:8080/a?b=c", "http://www.example.com:8080/a?b=c"},
+		// Default rules still apply
+		{"https://mobile.twitter.com/processone", "https://twitter.com/processone"},
+	}
+	for _, test := range tests {
+		if rewritten := rewriter.Rewrite(test.link); rewritten != test.expected {
+			t.Errorf("Incorrect rewrite for %s. Got: '%s' Expected: '%s'", test.link, rewritten, test.expected)
+		}
+	}
+
+	if err = ioutil.WriteFile(rulesFile, []byte(`[{"host": "example.com"}]`), 0644); err != nil {
+		t.Errorf("Cannot write rules: %s", err)
+		return
+	}
+	if _, err = semweb.LoadRewriter(rulesFile); err == nil {
+		t.Error("Rule without action should be rejected")
+	}
+}
@@ -0,0 +1,76 @@
+package semweb
+
+import (
+	"net/url"
+	"strings"
+)
+
+//=============================================================================
+// URL sanitization
+// Remove tracking parameters and normalize URLs
+
+// trackingParams are query parameters only used to track visitors.
+var trackingParams = map[string]bool{
+	"fbclid":  true, // Facebook
+	"gclid":   true, // Google Ads
+	"gclsrc":  true,
+	"dclid":   true,
+	"msclkid": true, // Microsoft Ads
+	"yclid":   true, // Yandex
+	"mc_cid":  true, // Mailchimp
+	"mc_eid":  true,
+	"igshid":  true, // Instagram
+	"_hsenc":  true, // HubSpot
+	"_hsmi":   true,
+	"mkt_tok": true, // Marketo
+	"ref_src": true, // Twitter
+	"ref_url": true,
+}
+
+// trackingPrefixes are prefixes of query parameters used to track visitors,
+// like Google Analytics utm_source or utm_campaign.
+var trackingPrefixes = []string{"utm_", "pk_"}
+
+// CleanUrl removes tracking parameters from link, lowercases its scheme and
+// host and removes its default port. Other query parameters are kept in their
+// original order. Invalid URLs are returned unchanged.
+func CleanUrl(link string) string {
+	u, err := url.Parse(link)
+	if err != nil || u.Host == "" {
+		return link
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	if port := u.Port(); (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		u.Host = strings.TrimSuffix(u.Host, ":"+port)
+	}
+
+	if u.RawQuery != "" {
+		var kept []string
+		for _, param := range strings.Split(u.RawQuery, "&") {
+			name := param
+			if i := strings.IndexByte(param, '='); i >= 0 {
+				name = param[:i]
+			}
+			if name, err = url.QueryUnescape(name); err == nil && isTrackingParam(name) {
+				continue
+			}
+			kept = append(kept, param)
+		}
+		u.RawQuery = strings.Join(kept, "&")
+	}
+	return u.String()
+}
+
+func isTrackingParam(name string) bool {
+	name = strings.ToLower(name)
+	if trackingParams[name] {
+		return true
+	}
+	for _, prefix := range trackingPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,28 @@
+package semweb_test
+
+import (
+	"testing"
+
+	"github.com/processone/dpk/pkg/semweb"
+)
+
+func TestCleanUrl(t *testing.T) {
+	tests := []struct {
+		link     string
+		expected string
+	}{
+		{"https://example.com/a?utm_source=twitter&utm_medium=social", "https://example.com/a"},
+		{"https://example.com/a?id=3&fbclid=IwAR0&b=c%20d#top", "https://example.com/a?id=3&b=c%20d#top"},
+		{"https://example.com/?gclid=1&mc_eid=2&mc_cid=3&UTM_Campaign=x", "https://example.com/"},
+		{"HTTPS://WWW.Example.COM:443/Path", "https://www.example.com/Path"},
+		{"http://example.com:80/", "http://example.com/"},
+		{"http://example.com:8080/", "http://example.com:8080/"},
+		{"https://example.com/search?q=utm_source", "https://example.com/search?q=utm_source"},
+		{"not a url", "not a url"},
+	}
+	for _, test := range tests {
+		if clean := semweb.CleanUrl(test.link); clean != test.expected {
+			t.Errorf("Incorrect clean URL for %s. Got: '%s' Expected: '%s'", test.link, clean, test.expected)
+		}
+	}
+}
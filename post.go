@@ -0,0 +1,212 @@
+package dpk
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"sort"
+	"strings"
+	"time"
+)
+
+//=============================================================================
+// Provider neutral post model
+//
+// Each provider importer converts its data export to a list of posts. Posts
+// are then written to disk in the same format, whatever their provider.
+
+// Post is a single piece of content, as published on a provider.
+type Post struct {
+	// Id is the identifier of the post at the provider.
+	Id string
+	// Provider is the name of the provider the post was imported from.
+	Provider string
+	// Type can be "microblog", "reply", "repost" or "quote".
+	Type string
+	// Section is the subdirectory of the output where the post is stored. It is
+	// empty for posts stored with the main posts.
+	Section string
+	Author  Author
+	// Content is the post body in Markdown. Attachments are referenced by their
+	// filename, as they are stored next to the post.
+	Content     string
+	Lang        string
+	Attachments []Attachment
+	// Links is the list of URLs the post links to.
+	Links []string
+	Tags  []string
+	// InReplyTo is the URL of the post this post replies to.
+	InReplyTo string
+	// SourceUrl is the URL of the post at the provider.
+	SourceUrl string
+	// Aliases are other paths the post can be reached at, relative to the site
+	// root, for site generators supporting redirections.
+	Aliases []string
+	// Mentions are the users mentioned in the post.
+	Mentions []Author
+	// Client is the application used to publish the post.
+	Client        Client
+	FavoriteCount int
+	RepostCount   int
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// Author is the person who published a post, or a user mentioned in a post.
+type Author struct {
+	Name   string `json:",omitempty"`
+	Handle string
+	Url    string `json:",omitempty"`
+}
+
+// Client is the application used to publish a post.
+type Client struct {
+	Name string
+	Url  string `json:",omitempty"`
+}
+
+// Attachment is a file attached to a post, like a photo or a video.
+type Attachment struct {
+	// Filename is the name of the attachment file, in the post directory.
+	Filename string
+	// Type can be "photo", "animated_gif", "video" or "thumbnail", for the
+	// preview image of a linked video or page.
+	Type        string
+	OriginalUrl string
+	// Width and Height are the media dimensions in pixels, when known.
+	Width   int
+	Height  int
+	AltText string
+	// Open gives access to the content of the attachment.
+	Open func() (io.ReadCloser, error) `json:"-"`
+}
+
+// addMention adds user to the post mentions, if not already mentioned.
+func (p *Post) addMention(user Author) {
+	for _, m := range p.Mentions {
+		if strings.EqualFold(m.Handle, user.Handle) {
+			return
+		}
+	}
+	p.Mentions = append(p.Mentions, user)
+}
+
+// Metadata returns the metadata stored along the post content.
+func (p Post) Metadata() Metadata {
+	metadata := Metadata{
+		SchemaVersion: MetadataVersion,
+		Type:          p.Type,
+		Lang:          p.Lang,
+		HashTags:      p.Tags,
+		InReplyTo:     p.InReplyTo,
+		SourceUrl:     p.SourceUrl,
+		Aliases:       p.Aliases,
+		CreatedAt:     p.CreatedAt,
+		Id:            p.Id,
+		Provider:      p.Provider,
+		FavoriteCount: p.FavoriteCount,
+		RepostCount:   p.RepostCount,
+		Mentions:      p.Mentions,
+		Links:         p.Links,
+	}
+	if p.UpdatedAt.After(p.CreatedAt) {
+		updatedAt := p.UpdatedAt
+		metadata.UpdatedAt = &updatedAt
+	}
+	if p.Client.Name != "" {
+		client := p.Client
+		metadata.Client = &client
+	}
+	for _, a := range p.Attachments {
+		metadata.Media = append(metadata.Media, MediaMetadata{
+			File:        a.Filename,
+			Type:        a.Type,
+			OriginalUrl: a.OriginalUrl,
+			Width:       a.Width,
+			Height:      a.Height,
+			AltText:     a.AltText,
+		})
+	}
+	return metadata
+}
+
+//=============================================================================
+// Post metadata struct for marshaling
+//
+// The same struct is used for metadata.json and for the front matter of posts.
+// Front matter keys follow Hugo naming, and only publication fields are part of
+// the front matter. The format of metadata.json is described by the JSON Schema
+// in schema/metadata.schema.json.
+
+// MetadataVersion is the version of the metadata.json format. It is increased
+// on each change of the schema.
+const MetadataVersion = 2
+
+type Metadata struct {
+	SchemaVersion int        `yaml:"-" toml:"-"`
+	Type          string     `yaml:"type" toml:"type"`
+	Lang          string     `yaml:"lang,omitempty" toml:"lang,omitempty"`
+	HashTags      []string   `json:",omitempty" yaml:"tags,omitempty" toml:"tags,omitempty"`
+	InReplyTo     string     `json:",omitempty" yaml:"inReplyTo,omitempty" toml:"inReplyTo,omitempty"`
+	SourceUrl     string     `json:",omitempty" yaml:"source,omitempty" toml:"source,omitempty"`
+	Aliases       []string   `json:",omitempty" yaml:"aliases,omitempty" toml:"aliases,omitempty"`
+	CreatedAt     time.Time  `yaml:"date" toml:"date"`
+	UpdatedAt     *time.Time `json:",omitempty" yaml:"lastmod,omitempty" toml:"lastmod,omitempty"`
+	// Detailed description of the post, only stored in metadata.json
+	Id            string          `yaml:"-" toml:"-"`
+	Provider      string          `yaml:"-" toml:"-"`
+	FavoriteCount int             `yaml:"-" toml:"-"`
+	RepostCount   int             `yaml:"-" toml:"-"`
+	Client        *Client         `json:",omitempty" yaml:"-" toml:"-"`
+	Mentions      []Author        `json:",omitempty" yaml:"-" toml:"-"`
+	Links         []string        `json:",omitempty" yaml:"-" toml:"-"`
+	Media         []MediaMetadata `json:",omitempty" yaml:"-" toml:"-"`
+}
+
+// MediaMetadata describes a media file attached to a post.
+type MediaMetadata struct {
+	// File is the name of the media file, in the post directory.
+	File        string
+	Type        string
+	OriginalUrl string `json:",omitempty"`
+	Width       int    `json:",omitempty"`
+	Height      int    `json:",omitempty"`
+	AltText     string `json:",omitempty"`
+}
+
+//=============================================================================
+// Importers
+
+// Importer reads the data export from a provider and converts it to posts.
+type Importer interface {
+	// Import returns the posts found in the archive, sorted by creation date.
+	Import(archive fs.FS) ([]Post, error)
+}
+
+var importers = make(map[string]func() Importer)
+
+// RegisterImporter makes an importer available for the given provider name.
+// newImporter returns a new importer, as importers keep the state of an
+// import. It is expected to be called from the init function of the importer.
+func RegisterImporter(provider string, newImporter func() Importer) {
+	importers[provider] = newImporter
+}
+
+// GetImporter returns a new importer for provider.
+func GetImporter(provider string) (Importer, error) {
+	newImporter, ok := importers[provider]
+	if !ok {
+		return nil, fmt.Errorf("unknown provider: %s", provider)
+	}
+	return newImporter(), nil
+}
+
+// Importers returns the sorted list of providers with a registered importer.
+func Importers() []string {
+	var providers []string
+	for provider := range importers {
+		providers = append(providers, provider)
+	}
+	sort.Strings(providers)
+	return providers
+}
@@ -0,0 +1,158 @@
+package dpk_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/processone/dpk"
+)
+
+func TestTwitterImporter(t *testing.T) {
+	importer, err := dpk.GetImporter("twitter")
+	if err != nil {
+		t.Errorf("Twitter importer is not registered: %s", err)
+		return
+	}
+	if _, err = dpk.GetImporter("unknown"); err == nil {
+		t.Error("Unknown provider should return an error")
+	}
+	if other, _ := dpk.GetImporter("twitter"); other == importer {
+		t.Error("Importers should not be shared")
+	}
+
+	posts, err := importer.Import(os.DirFS("fixtures/twitter-2022"))
+	if err != nil {
+		t.Errorf("Cannot import archive: %s", err)
+		return
+	}
+	// The self-thread is imported as a single post and the reply is skipped
+	if len(posts) != 4 {
+		t.Errorf("Incorrect number of posts: %d", len(posts))
+		return
+	}
+	for _, post := range posts {
+		if post.Provider != "twitter" || post.Author.Handle != "processone" {
+			t.Errorf("Incorrect post provider or author: %s %+v", post.Provider, post.Author)
+		}
+		if post.SourceUrl != "https://twitter.com/processone/status/"+post.Id {
+			t.Errorf("Incorrect post source URL: %s", post.SourceUrl)
+		}
+	}
+
+	// The photo is attached to the post and can be read from the archive
+	photo := posts[1]
+	if len(photo.Attachments) != 1 || photo.Attachments[0].Filename != "1587200000000000000-FgXyZ12WAAEaBcD.jpg" {
+		t.Errorf("Incorrect attachments: %+v", photo.Attachments)
+		return
+	}
+	f, err := photo.Attachments[0].Open()
+	if err != nil {
+		t.Errorf("Cannot open attachment: %s", err)
+		return
+	}
+	f.Close()
+
+	thread := posts[2]
+	if !thread.UpdatedAt.After(thread.CreatedAt) {
+		t.Errorf("Thread update date %s should be after its creation date %s", thread.UpdatedAt, thread.CreatedAt)
+	}
+}
+
+func TestMetadataSchema(t *testing.T) {
+	data, err := ioutil.ReadFile("schema/metadata.schema.json")
+	if err != nil {
+		t.Errorf("Cannot read metadata schema: %s", err)
+		return
+	}
+	var schema map[string]interface{}
+	if err = json.Unmarshal(data, &schema); err != nil {
+		t.Errorf("Cannot parse metadata schema: %s", err)
+		return
+	}
+
+	outputDir := t.TempDir()
+	if err = dpk.TwitterToMD("fixtures/twitter-2022", outputDir, dpk.TwitterOptions{Replies: dpk.KeepReplies}); err != nil {
+		t.Errorf("Cannot convert archive: %s", err)
+		return
+	}
+	files, _ := filepath.Glob(filepath.Join(outputDir, "*", "*", "*", "*", "metadata.json"))
+	if len(files) != 5 {
+		t.Errorf("Incorrect number of metadata files: %d", len(files))
+	}
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Errorf("Cannot read metadata: %s", err)
+			continue
+		}
+		var metadata interface{}
+		if err = json.Unmarshal(data, &metadata); err != nil {
+			t.Errorf("Cannot parse metadata: %s", err)
+			continue
+		}
+		for _, e := range checkSchema(schema, metadata, "") {
+			t.Errorf("%s: %s", file, e)
+		}
+	}
+
+	// Media are described in metadata
+	data, err = ioutil.ReadFile(filepath.Join(outputDir, "2022", "10", "31", "002", "metadata.json"))
+	if err != nil {
+		t.Errorf("Cannot read metadata: %s", err)
+		return
+	}
+	var metadata dpk.Metadata
+	if err = json.Unmarshal(data, &metadata); err != nil {
+		t.Errorf("Cannot parse metadata: %s", err)
+		return
+	}
+	expected := dpk.MediaMetadata{
+		File:        "1587200000000000000-FgXyZ12WAAEaBcD.jpg",
+		Type:        "photo",
+		OriginalUrl: "https://pbs.twimg.com/media/FgXyZ12WAAEaBcD.jpg",
+		Width:       1,
+		Height:      1,
+		AltText:     "The Seine river in Paris",
+	}
+	if len(metadata.Media) != 1 || metadata.Media[0] != expected {
+		t.Errorf("Incorrect media metadata: %+v", metadata.Media)
+	}
+	if metadata.Id != "1587200000000000000" || metadata.FavoriteCount != 15 ||
+		metadata.Client == nil || metadata.Client.Name != "Twitter for Android" {
+		t.Errorf("Incorrect metadata: %+v", metadata)
+	}
+}
+
+// checkSchema checks the required and allowed properties of value against a
+// JSON Schema, which is enough to detect a schema out of sync with metadata.
+func checkSchema(schema map[string]interface{}, value interface{}, path string) []string {
+	var errors []string
+	switch v := value.(type) {
+	case map[string]interface{}:
+		properties, _ := schema["properties"].(map[string]interface{})
+		required, _ := schema["required"].([]interface{})
+		for _, r := range required {
+			if _, ok := v[r.(string)]; !ok {
+				errors = append(errors, fmt.Sprintf("missing property %s%s", path, r))
+			}
+		}
+		for key, child := range v {
+			s, ok := properties[key].(map[string]interface{})
+			if !ok {
+				errors = append(errors, fmt.Sprintf("property %s%s is not in schema", path, key))
+				continue
+			}
+			errors = append(errors, checkSchema(s, child, path+key+".")...)
+		}
+	case []interface{}:
+		items, _ := schema["items"].(map[string]interface{})
+		for _, child := range v {
+			errors = append(errors, checkSchema(items, child, path)...)
+		}
+	}
+	return errors
+}
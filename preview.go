@@ -0,0 +1,124 @@
+package dpk
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/processone/dpk/pkg/semweb"
+)
+
+//=============================================================================
+// Link preview cards
+//
+// Links can be rendered as preview cards, with the title, description, site
+// name and image of the target page, read from its metadata. The image is
+// stored beside the post, so that posts still make sense after the target site
+// is gone.
+
+// linkCard is the preview of the page a link points to.
+type linkCard struct {
+	Title       string
+	Description string
+	SiteName    string
+	// ImageUrl is the URL of the page image, stored in Image.
+	ImageUrl string
+	Image    []byte
+}
+
+// imageExtensions are the extensions kept for downloaded images.
+var imageExtensions = map[string]bool{".jpg": true, ".jpeg": true, ".png": true, ".gif": true, ".webp": true}
+
+// previewCard returns the preview card of the page link points to, from the
+// client cache when possible, or nil if the page has no title.
+func previewCard(client semweb.Client, link string) *linkCard {
+	entry, err := client.ResolveLink(link)
+	if err != nil || entry.Title == "" {
+		return nil
+	}
+	card := linkCard{
+		Title:       entry.Title,
+		Description: entry.Description,
+		SiteName:    entry.SiteName,
+	}
+	if entry.Image == "" {
+		return &card
+	}
+
+	if data, ok := client.Cache.GetFile(entry.Image); ok {
+		card.ImageUrl, card.Image = entry.Image, data
+		return &card
+	}
+	res, err := client.Fetch(entry.Image)
+	if err != nil || res.Status != 200 {
+		return &card
+	}
+	if err = client.Cache.PutFile(entry.Image, res.Body); err != nil {
+		fmt.Println("Cannot cache preview image:", err)
+	}
+	card.ImageUrl, card.Image = entry.Image, res.Body
+	return &card
+}
+
+// imageFilename returns the name of the file storing the image downloaded from
+// imageUrl, based on its hash.
+func imageFilename(imageUrl string) string {
+	ext := ".jpg"
+	if u, err := url.Parse(imageUrl); err == nil && imageExtensions[strings.ToLower(path.Ext(u.Path))] {
+		ext = strings.ToLower(path.Ext(u.Path))
+	}
+	hash := sha256.Sum256([]byte(imageUrl))
+	return "preview-" + hex.EncodeToString(hash[:6]) + ext
+}
+
+// cardToMd renders a link as a preview card, in HTML, with its image attached
+// to post. The card is a separate HTML block, surrounded by blank lines, so
+// that the text following it is still rendered as Markdown.
+func cardToMd(post *Post, card linkCard, link string) string {
+	var preview strings.Builder
+	preview.WriteString("\n\n<div class=\"link-preview\">\n")
+	if card.Image != nil {
+		filename := imageFilename(card.ImageUrl)
+		if !hasAttachment(*post, filename) {
+			var width, height int
+			if config, _, err := image.DecodeConfig(bytes.NewReader(card.Image)); err == nil {
+				width, height = config.Width, config.Height
+			}
+			data := card.Image
+			post.Attachments = append(post.Attachments, Attachment{
+				Filename:    filename,
+				Type:        "thumbnail",
+				OriginalUrl: card.ImageUrl,
+				Width:       width,
+				Height:      height,
+				AltText:     card.Title,
+				Open: func() (io.ReadCloser, error) {
+					return ioutil.NopCloser(bytes.NewReader(data)), nil
+				},
+			})
+		}
+		preview.WriteString(fmt.Sprintf("<a href=\"%s\"><img src=\"%s\" alt=\"\"></a>\n",
+			html.EscapeString(link), filename))
+	}
+	preview.WriteString(fmt.Sprintf("<p><a href=\"%s\"><strong>%s</strong></a></p>\n",
+		html.EscapeString(link), html.EscapeString(card.Title)))
+	if card.Description != "" {
+		preview.WriteString(fmt.Sprintf("<p>%s</p>\n", html.EscapeString(card.Description)))
+	}
+	if card.SiteName != "" {
+		preview.WriteString(fmt.Sprintf("<p><small>%s</small></p>\n", html.EscapeString(card.SiteName)))
+	}
+	preview.WriteString("</div>\n\n")
+	return preview.String()
+}
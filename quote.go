@@ -0,0 +1,96 @@
+package dpk
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+//=============================================================================
+// Retweets and quoted tweets
+
+// statusPath matches the path of a tweet permalink and captures the tweet ID.
+var statusPath = regexp.MustCompile(`^/(?:\w+|i/web)/status(?:es)?/([0-9]+)`)
+
+// tweetType returns the metadata type of a tweet: "repost" for retweets, "quote"
+// for tweets quoting another tweet and "microblog" for other tweets.
+func tweetType(tweet Tweet) string {
+	if tweet.RetweetedStatus != nil || isRetweet(tweet) {
+		return "repost"
+	}
+	if tweet.QuotedStatusId != "" || tweet.QuotedStatus != nil {
+		return "quote"
+	}
+	// Archives only keep quoted tweets as links to their permalink
+	for _, u := range tweet.Entities.Urls {
+		if statusId(u.ExpandedUrl) != "" {
+			return "quote"
+		}
+	}
+	return "microblog"
+}
+
+// statusId returns the ID of the tweet a permalink points to, or an empty
+// string if link is not a tweet permalink.
+func statusId(link string) string {
+	u, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+	switch strings.ToLower(u.Host) {
+	case "twitter.com", "www.twitter.com", "mobile.twitter.com", "x.com":
+	default:
+		return ""
+	}
+	matches := statusPath.FindStringSubmatch(u.Path)
+	if matches == nil {
+		return ""
+	}
+	return matches[1]
+}
+
+// quotedTweet returns the tweet from the archive that link points to.
+func (c twitterConverter) quotedTweet(link string) (Tweet, bool) {
+	id := statusId(link)
+	if id == "" {
+		return Tweet{}, false
+	}
+	tweet, ok := c.tweets[id]
+	return tweet, ok
+}
+
+// quoteToMd renders a tweet as a Markdown blockquote, followed by a link to the
+// original tweet. Media from the quoted tweet are attached to post.
+func (c twitterConverter) quoteToMd(quoted Tweet, post *Post) string {
+	mediafiles := c.addMedia(quoted, post)
+	text := c.tweetToMd(quoted, post, mediafiles, false)
+
+	author := c.account.Username
+	if quoted.User != nil {
+		author = quoted.User.ScreenName
+	}
+	timestamp := quoted.Timestamp
+	if timestamp.IsZero() {
+		// Tweets embedded in other tweets do not have their date parsed
+		timestamp, _ = rubyDateToTime(quoted.CreatedAt)
+	}
+
+	var quote strings.Builder
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		if line == "" {
+			quote.WriteString(">\n")
+		} else {
+			quote.WriteString("> " + line + "\n")
+		}
+	}
+	label := "Tweet"
+	if author != "" {
+		label = "@" + author
+	}
+	if !timestamp.IsZero() {
+		label += ", " + timestamp.Format("2 Jan 2006")
+	}
+	quote.WriteString(fmt.Sprintf(">\n> — [%s](%s)", label, tweetUrl(author, quoted.Id)))
+	return quote.String()
+}
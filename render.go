@@ -0,0 +1,230 @@
+package dpk
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/processone/dpk/pkg/semweb"
+)
+
+//=============================================================================
+// Tweet rendering
+//
+// Tweets are rendered in a single pass over their text: entities (links,
+// media, hashtags, cashtags and mentions) are located using their indices and
+// rendered in place, while the plain text between them is escaped for Markdown.
+// Twitter counts indices in UTF-16 code units, so the text is walked as UTF-16.
+
+// textEntity is a part of the tweet text, between start and end indices, to
+// render as Markdown.
+type textEntity struct {
+	start, end int
+	markdown   func() string
+}
+
+// tweetToMd renders the tweet as Markdown, with its media attached to post.
+// When embedQuotes is true, tweets from the archive quoted by the tweet are
+// rendered inline, as blockquotes.
+func (c twitterConverter) tweetToMd(tweet Tweet, post *Post, mediafiles []localMedia, embedQuotes bool) string {
+	if tweet.RetweetedStatus != nil {
+		return c.quoteToMd(*tweet.RetweetedStatus, post)
+	}
+
+	text := utf16.Encode([]rune(tweet.FullText))
+	var entities []textEntity
+	add := func(indices []string, value string, markdown func() string) {
+		start, end, ok := locateEntity(text, indices, value)
+		if ok {
+			entities = append(entities, textEntity{start: start, end: end, markdown: markdown})
+		}
+	}
+
+	for _, u := range tweet.Entities.Urls {
+		u := u
+		add(u.Indices, u.Url, func() string {
+			// Replace Twitter URLs with original URLs
+			if quoted, ok := c.quotedTweet(u.ExpandedUrl); ok && embedQuotes {
+				post.Links = appendUnique(post.Links, semweb.CleanUrl(u.ExpandedUrl))
+				return "\n\n" + c.quoteToMd(quoted, post) + "\n\n"
+			}
+			markdown, target := c.renderLink(post, u.DisplayUrl, u.ExpandedUrl)
+			post.Links = appendUnique(post.Links, target)
+			return markdown
+		})
+	}
+	// All the media of a tweet share the same URL: Twitter URL for media is
+	// replaced with the rendering of all media.
+	if media := tweetMedia(tweet); len(media) > 0 {
+		add(media[0].Indices, media[0].Url, func() string {
+			return mediaToMd(mediafiles)
+		})
+	}
+	for _, h := range tweet.Entities.HashTags {
+		h := h
+		add(h.Indices, "#"+h.Text, func() string {
+			return fmt.Sprintf("[%s](%s)", escapeMarkdown("#"+h.Text, false), tagLink(h.Text))
+		})
+	}
+	for _, s := range tweet.Entities.Symbols {
+		s := s
+		add(s.Indices, "$"+s.Text, func() string {
+			return fmt.Sprintf("[%s](https://twitter.com/search?q=%%24%s)", escapeMarkdown("$"+s.Text, false), url.QueryEscape(s.Text))
+		})
+	}
+	for _, m := range tweet.Entities.UserMentions {
+		m := m
+		add(m.Indices, "@"+m.ScreenName, func() string {
+			return mentionToMd(m)
+		})
+	}
+	sort.Slice(entities, func(i, j int) bool { return entities[i].start < entities[j].start })
+
+	// Reply mentions added by Twitter at the beginning of the text are not
+	// displayed: the displayed text starts a line.
+	start := 0
+	if len(tweet.DisplayTextRange) == 2 {
+		if s, err := strconv.Atoi(tweet.DisplayTextRange[0]); err == nil && s <= len(text) {
+			start = s
+		}
+	}
+	pos := start
+
+	var markdown strings.Builder
+	for _, e := range entities {
+		if e.start < pos {
+			// Overlapping or hidden entity
+			continue
+		}
+		markdown.WriteString(textToMd(text[pos:e.start], pos == start || isNewline(text[pos-1])))
+		markdown.WriteString(e.markdown())
+		pos = e.end
+	}
+	markdown.WriteString(textToMd(text[pos:], pos == start || isNewline(text[pos-1])))
+	return markdown.String()
+}
+
+// mentionToMd renders a mention as a link to the Twitter profile of the user,
+// with the user name as title.
+func mentionToMd(m Mention) string {
+	link := fmt.Sprintf("https://twitter.com/%s", m.ScreenName)
+	if m.Name == "" {
+		return fmt.Sprintf("[%s](%s)", escapeMarkdown("@"+m.ScreenName, false), link)
+	}
+	title := strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(m.Name)
+	return fmt.Sprintf("[%s](%s \"%s\")", escapeMarkdown("@"+m.ScreenName, false), link, title)
+}
+
+// tweetMedia returns the media of the tweet, from extended entities when available.
+func tweetMedia(tweet Tweet) []Media {
+	if len(tweet.ExtendedEntities.Media) > 0 {
+		return tweet.ExtendedEntities.Media
+	}
+	return tweet.Entities.Media
+}
+
+// locateEntity returns the position of an entity in the UTF-16 encoded text. It
+// uses the entity indices when they match the expected entity value, and falls
+// back to searching for the value in text otherwise, for example when the text
+// has been restored after being truncated.
+func locateEntity(text []uint16, indices []string, value string) (int, int, bool) {
+	if len(indices) == 2 {
+		start, err1 := strconv.Atoi(indices[0])
+		end, err2 := strconv.Atoi(indices[1])
+		if err1 == nil && err2 == nil && 0 <= start && start < end && end <= len(text) {
+			if strings.EqualFold(string(utf16.Decode(text[start:end])), value) {
+				return start, end, true
+			}
+		}
+	}
+
+	needle := utf16.Encode([]rune(value))
+	for i := 0; i+len(needle) <= len(text); i++ {
+		if strings.EqualFold(string(utf16.Decode(text[i:i+len(needle)])), value) {
+			return i, i + len(needle), true
+		}
+	}
+	return 0, 0, false
+}
+
+func isNewline(c uint16) bool {
+	return c == '\n'
+}
+
+// textToMd renders a plain text segment of a tweet as Markdown.
+func textToMd(segment []uint16, lineStart bool) string {
+	lines := strings.Split(string(utf16.Decode(segment)), "\n")
+	for i, line := range lines {
+		lines[i] = escapeMarkdown(line, lineStart || i > 0)
+	}
+	// Insert two spaces at end of line to generate Markdown line break
+	return strings.Join(lines, "  \n")
+}
+
+// escapeMarkdown escapes characters with a special meaning in Markdown. When
+// lineStart is true, line is at the beginning of a line, where more characters
+// can start a Markdown block (heading, quote, list).
+func escapeMarkdown(line string, lineStart bool) string {
+	var escaped strings.Builder
+	for i, r := range line {
+		switch r {
+		case '\\', '`', '*', '_', '[', ']', '<', '~':
+			escaped.WriteRune('\\')
+		case '#', '>', '-', '+':
+			if lineStart && strings.TrimSpace(line[:i]) == "" {
+				escaped.WriteRune('\\')
+			}
+		case '.', ')':
+			// Ordered list item
+			if lineStart && i > 0 && isDigits(strings.TrimSpace(line[:i])) {
+				escaped.WriteRune('\\')
+			}
+		}
+		escaped.WriteRune(r)
+	}
+	return escaped.String()
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// mediaToMd renders the tweet media, stored next to the post.
+func mediaToMd(mediafiles []localMedia) string {
+	var markdown strings.Builder
+	for i, media := range mediafiles {
+		fullpath := media.filename
+		switch media.mediaType {
+		case "photo":
+			markdown.WriteString(fmt.Sprintf("\n![attachment %2d](%s)\n", i, fullpath))
+		case "video":
+			template := `
+<video controls>
+ <source src="%s" type="video/mp4">
+ Your browser does not support the video tag.
+</video>
+`
+			markdown.WriteString(fmt.Sprintf(template, fullpath))
+		case "animated_gif":
+			template := `
+<video controls loop>
+ <source src="%s" type="video/mp4">
+ Your browser does not support the video tag.
+</video>
+`
+			markdown.WriteString(fmt.Sprintf(template, fullpath))
+		}
+	}
+	return markdown.String()
+}
@@ -0,0 +1,479 @@
+package dpk_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/processone/dpk"
+	"github.com/processone/dpk/pkg/semweb"
+)
+
+// tweetPostDir is the directory of the post converted by convertTweet,
+// relative to the output directory.
+var tweetPostDir = filepath.Join("2019", "01", "07", "001")
+
+// convertTweet converts an archive holding a single tweet, with the given
+// entities, as JSON, and text. It returns the Markdown of the post and the
+// output directory.
+func convertTweet(t *testing.T, entities, text string, options dpk.TwitterOptions) (string, string) {
+	t.Helper()
+	fullText, err := json.Marshal(text)
+	if err != nil {
+		t.Fatalf("Cannot encode tweet text: %s", err)
+	}
+	return convertTweetJSON(t, `{
+  "entities" : `+entities+`,
+  "id_str" : "1",
+  "created_at" : "Mon Jan 07 10:00:00 +0000 2019",
+  "full_text" : `+string(fullText)+`,
+  "lang" : "en"
+}`, options)
+}
+
+// convertTweetJSON converts an archive holding a single tweet, created on
+// 2019-01-07, and returns the Markdown of the post and the output directory.
+func convertTweetJSON(t *testing.T, tweet string, options dpk.TwitterOptions) (string, string) {
+	t.Helper()
+	archive := fstest.MapFS{
+		"tweet.js": &fstest.MapFile{Data: []byte("window.YTD.tweet.part0 = [ " + tweet + " ]")},
+	}
+	outputDir := t.TempDir()
+	if err := dpk.TwitterArchiveToMD(archive, outputDir, options); err != nil {
+		t.Fatalf("Cannot convert archive: %s", err)
+	}
+	post, err := ioutil.ReadFile(filepath.Join(outputDir, tweetPostDir, "post.md"))
+	if err != nil {
+		t.Fatalf("Cannot read converted post: %s", err)
+	}
+	return string(post), outputDir
+}
+
+func TestTweetRendering(t *testing.T) {
+	// Emoji count as two UTF-16 code units in Twitter indices. The same link
+	// appears twice and Markdown characters must be escaped.
+	entities := `{
+  "urls" : [ {
+    "url" : "https://t.co/AAAAAAAAAA",
+    "expanded_url" : "https://example.com/a",
+    "display_url" : "example.com/a",
+    "indices" : [ "28", "51" ]
+  }, {
+    "url" : "https://t.co/AAAAAAAAAA",
+    "expanded_url" : "https://example.com/a",
+    "display_url" : "example.com/a",
+    "indices" : [ "62", "85" ]
+  } ]
+}`
+	text := "🎉 *New* release_candidate: https://t.co/AAAAAAAAAA and again https://t.co/AAAAAAAAAA\n# not a title"
+	post, _ := convertTweet(t, entities, text, dpk.TwitterOptions{})
+
+	expected := "🎉 \\*New\\* release\\_candidate: [example.com/a](https://example.com/a) and again " +
+		"[example.com/a](https://example.com/a)  \n\\# not a title"
+	if post != expected {
+		t.Errorf("Incorrect tweet rendering. Got: '%s' Expected: '%s'", post, expected)
+	}
+}
+
+func TestReplyRendering(t *testing.T) {
+	// The displayed text of a reply starts after the mentions added by Twitter,
+	// at the beginning of a line.
+	post, _ := convertTweetJSON(t, `{
+  "display_text_range" : [ "7", "26" ],
+  "entities" : { },
+  "id_str" : "1",
+  "in_reply_to_status_id_str" : "2",
+  "in_reply_to_screen_name" : "alice",
+  "created_at" : "Mon Jan 07 10:00:00 +0000 2019",
+  "full_text" : "@alice - not a list\n- item",
+  "lang" : "en"
+}`, dpk.TwitterOptions{Replies: dpk.KeepReplies})
+
+	expected := "In reply to [@alice](https://twitter.com/alice/status/2)\n\n\\- not a list  \n\\- item"
+	if post != expected {
+		t.Errorf("Incorrect reply rendering. Got: '%s' Expected: '%s'", post, expected)
+	}
+}
+
+func TestTweetEntitiesRendering(t *testing.T) {
+	entities := `{
+  "hashtags" : [ {
+    "text" : "XMPP",
+    "indices" : [ "24", "29" ]
+  }, {
+    "text" : "xmpp",
+    "indices" : [ "51", "56" ]
+  } ],
+  "symbols" : [ {
+    "text" : "TSLA",
+    "indices" : [ "41", "46" ]
+  } ],
+  "user_mentions" : [ {
+    "name" : "Mickaël \"mremond\" Rémond",
+    "screen_name" : "mickael",
+    "indices" : [ "7", "15" ]
+  } ]
+}`
+	text := "Thanks @mickael for the #XMPP talk about $TSLA and #xmpp!"
+	post, outputDir := convertTweet(t, entities, text, dpk.TwitterOptions{})
+
+	tagPage := "/tags/xmpp.md"
+	expected := `Thanks [@mickael](https://twitter.com/mickael "Mickaël \"mremond\" Rémond") for the ` +
+		"[#XMPP](" + tagPage + ") talk about [$TSLA](https://twitter.com/search?q=%24TSLA) and [#xmpp](" + tagPage + ")!"
+	if post != expected {
+		t.Errorf("Incorrect entities rendering. Got: '%s' Expected: '%s'", post, expected)
+	}
+
+	// Hashtags are listed in metadata and in the tag index page
+	data, err := ioutil.ReadFile(filepath.Join(outputDir, tweetPostDir, "metadata.json"))
+	if err != nil {
+		t.Errorf("Cannot read metadata: %s", err)
+		return
+	}
+	var metadata dpk.Metadata
+	if err = json.Unmarshal(data, &metadata); err != nil {
+		t.Errorf("Cannot parse metadata: %s", err)
+		return
+	}
+	if len(metadata.HashTags) != 1 || metadata.HashTags[0] != "XMPP" {
+		t.Errorf("Incorrect hashtags in metadata: %v", metadata.HashTags)
+	}
+	page, err := ioutil.ReadFile(filepath.Join(outputDir, "tags", "xmpp.md"))
+	if err != nil {
+		t.Errorf("Tag index page was not generated: %s", err)
+		return
+	}
+	if !strings.Contains(string(page), "/2019/01/07/001/post.md") {
+		t.Errorf("Tag index page does not link to post: %s", page)
+	}
+	list, err := ioutil.ReadFile(filepath.Join(outputDir, "tags", "all-tags.md"))
+	if err != nil || !strings.Contains(string(list), "[#XMPP]("+tagPage+") (1)") {
+		t.Errorf("Tag list does not link to tag index page: %s %v", list, err)
+	}
+}
+
+func TestLinkCache(t *testing.T) {
+	entities := `{
+  "urls" : [ {
+    "url" : "https://t.co/AAAAAAAAAA",
+    "expanded_url" : "https://bit.ly/2ABCDEF",
+    "display_url" : "bit.ly/2ABCDEF",
+    "indices" : [ "5", "28" ]
+  }, {
+    "url" : "https://t.co/BBBBBBBBBB",
+    "expanded_url" : "https://bit.ly/2GHIJKL",
+    "display_url" : "bit.ly/2GHIJKL",
+    "indices" : [ "33", "56" ]
+  } ]
+}`
+	text := "Read https://t.co/AAAAAAAAAA and https://t.co/BBBBBBBBBB"
+
+	// Links are resolved from cache only, in offline mode
+	cache, err := semweb.OpenCache(filepath.Join(t.TempDir(), "links.json"), time.Hour)
+	if err != nil {
+		t.Errorf("Cannot open cache: %s", err)
+		return
+	}
+	cache.Offline = true
+	cache.Put(semweb.CacheEntry{
+		Url:      "https://bit.ly/2ABCDEF",
+		FinalUrl: "https://www.process-one.net/blog/",
+		Status:   200,
+		Title:    "ProcessOne [Blog] *news*",
+	})
+
+	// Markdown characters of page titles are escaped in link text
+	post, _ := convertTweet(t, entities, text, dpk.TwitterOptions{LinkCache: cache})
+	expected := "Read [ProcessOne \\[Blog\\] \\*news\\*](https://www.process-one.net/blog/) and [bit.ly/2GHIJKL](https://bit.ly/2GHIJKL)"
+	if post != expected {
+		t.Errorf("Incorrect cached link rendering. Got: '%s' Expected: '%s'", post, expected)
+	}
+}
+
+func TestLinkSanitization(t *testing.T) {
+	entities := `{
+  "urls" : [ {
+    "url" : "https://t.co/AAAAAAAAAA",
+    "expanded_url" : "https://Example.com:443/a?id=3&utm_source=twitter&fbclid=IwAR0",
+    "display_url" : "example.com/a?id=3",
+    "indices" : [ "5", "28" ]
+  }, {
+    "url" : "https://t.co/BBBBBBBBBB",
+    "expanded_url" : "https://bit.ly/2ABCDEF",
+    "display_url" : "bit.ly/2ABCDEF",
+    "indices" : [ "33", "56" ]
+  } ]
+}`
+	text := "Read https://t.co/AAAAAAAAAA and https://t.co/BBBBBBBBBB"
+
+	cache := &semweb.Cache{Offline: true}
+	cache.Put(semweb.CacheEntry{
+		Url:       "https://bit.ly/2ABCDEF",
+		FinalUrl:  "https://www.process-one.net/blog/?utm_campaign=launch",
+		Status:    200,
+		Title:     "ProcessOne Blog",
+		Canonical: "https://www.process-one.net/en/blog/",
+	})
+
+	tests := []struct {
+		canonical bool
+		expected  string
+	}{
+		{false, "Read [example.com/a?id=3](https://example.com/a?id=3) and [ProcessOne Blog](https://www.process-one.net/blog/)"},
+		{true, "Read [example.com/a?id=3](https://example.com/a?id=3) and [ProcessOne Blog](https://www.process-one.net/en/blog/)"},
+	}
+	for _, test := range tests {
+		options := dpk.TwitterOptions{LinkCache: cache, CanonicalLinks: test.canonical}
+		if post, _ := convertTweet(t, entities, text, options); post != test.expected {
+			t.Errorf("Incorrect link sanitization. Got: '%s' Expected: '%s'", post, test.expected)
+		}
+	}
+}
+
+func TestLinkRewrite(t *testing.T) {
+	entities := `{
+  "urls" : [ {
+    "url" : "https://t.co/AAAAAAAAAA",
+    "expanded_url" : "http://m.engadget.com/default/article.do?artUrl=http://www.engadget.com/2011/02/08/nokia-elop/&category=classic",
+    "display_url" : "m.engadget.com/default/articl…",
+    "indices" : [ "5", "28" ]
+  } ]
+}`
+	text := "Read https://t.co/AAAAAAAAAA"
+
+	post, _ := convertTweet(t, entities, text, dpk.TwitterOptions{})
+	expected := "Read [www.engadget.com/2011/02/08/nokia-elop/](http://www.engadget.com/2011/02/08/nokia-elop/)"
+	if post != expected {
+		t.Errorf("Incorrect rewritten link. Got: '%s' Expected: '%s'", post, expected)
+	}
+}
+
+func TestYouTubeLinks(t *testing.T) {
+	entities := `{
+  "urls" : [ {
+    "url" : "https://t.co/AAAAAAAAAA",
+    "expanded_url" : "https://youtu.be/dQw4w9WgXcQ",
+    "display_url" : "youtu.be/dQw4w9WgXcQ",
+    "indices" : [ "6", "29" ]
+  } ]
+}`
+	text := "Watch https://t.co/AAAAAAAAAA"
+
+	// Video title and thumbnail are read from cache
+	cache := &semweb.Cache{Offline: true}
+	cache.Put(semweb.CacheEntry{
+		Url:      "https://www.youtube.com/watch?v=dQw4w9WgXcQ",
+		FinalUrl: "https://www.youtube.com/watch?v=dQw4w9WgXcQ",
+		Status:   200,
+		Title:    "ejabberd [Demo]",
+	})
+	thumbnail := []byte("JPEG data")
+	if err := cache.PutFile("https://i.ytimg.com/vi/dQw4w9WgXcQ/hqdefault.jpg", thumbnail); err != nil {
+		t.Errorf("Cannot cache thumbnail: %s", err)
+		return
+	}
+
+	tests := []struct {
+		facade   bool
+		expected string
+	}{
+		{false, "Watch \n[![ejabberd \\[Demo\\]](youtube-dQw4w9WgXcQ.jpg)](https://www.youtube.com/watch?v=dQw4w9WgXcQ)\n"},
+		{true, "Watch \n<a class=\"youtube-facade\" href=\"https://www.youtube.com/watch?v=dQw4w9WgXcQ\" " +
+			"onclick=\"this.outerHTML='&lt;iframe width=&#34;480&#34; height=&#34;360&#34; " +
+			"src=&#34;https://www.youtube-nocookie.com/embed/dQw4w9WgXcQ?autoplay=1&#34; " +
+			"allow=&#34;autoplay; encrypted-media; picture-in-picture&#34; allowfullscreen&gt;&lt;/iframe&gt;'; return false;\">" +
+			"<img src=\"youtube-dQw4w9WgXcQ.jpg\" alt=\"ejabberd [Demo]\" width=\"480\" height=\"360\"></a>\n"},
+	}
+	for _, test := range tests {
+		options := dpk.TwitterOptions{LinkCache: cache, YouTubeFacade: test.facade}
+		post, outputDir := convertTweet(t, entities, text, options)
+		if post != test.expected {
+			t.Errorf("Incorrect video rendering. Got: '%s' Expected: '%s'", post, test.expected)
+		}
+		data, err := ioutil.ReadFile(filepath.Join(outputDir, tweetPostDir, "youtube-dQw4w9WgXcQ.jpg"))
+		if err != nil || string(data) != string(thumbnail) {
+			t.Errorf("Video thumbnail not stored with post: %v", err)
+		}
+	}
+}
+
+func TestOEmbedLinks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/oembed":
+			fmt.Fprint(w, `{"type": "video", "version": "1.0", "html": `+
+				`"<iframe src=\"https://player.example.com/1\" width=\"640\" height=\"360\" onload=\"track()\"></iframe><script src=\"https://example.com/widget.js\"></script>"}`)
+		default:
+			fmt.Fprint(w, `<html><head><link rel="alternate" type="application/json+oembed" href="/oembed?url=talk"></head></html>`)
+		}
+	}))
+	defer server.Close()
+
+	entities := `{
+  "urls" : [ {
+    "url" : "https://t.co/AAAAAAAAAA",
+    "expanded_url" : "https://vimeo.com/76979871",
+    "display_url" : "vimeo.com/76979871",
+    "indices" : [ "6", "29" ]
+  }, {
+    "url" : "https://t.co/BBBBBBBBBB",
+    "expanded_url" : "` + server.URL + `/talk",
+    "display_url" : "example.com/talk",
+    "indices" : [ "34", "57" ]
+  } ]
+}`
+	text := "Watch https://t.co/AAAAAAAAAA and https://t.co/BBBBBBBBBB"
+
+	// Vimeo embed is read from cache, the other one is discovered in the page
+	cache := &semweb.Cache{}
+	cache.Put(semweb.CacheEntry{
+		Url:    "https://vimeo.com/api/oembed.json?url=https%3A%2F%2Fvimeo.com%2F76979871",
+		Status: 200,
+		OEmbed: `<iframe src="https://player.vimeo.com/video/76979871"></iframe>`,
+	})
+
+	// Players are only kept when enabled
+	tests := []struct {
+		players  bool
+		expected string
+	}{
+		{false, "Watch [vimeo.com/76979871](https://vimeo.com/76979871) and [example.com/talk](" + server.URL + "/talk)"},
+		{true, "Watch \n\n<iframe src=\"https://player.vimeo.com/video/76979871\"></iframe>\n\n and \n\n" +
+			"<iframe src=\"https://player.example.com/1\" width=\"640\" height=\"360\"></iframe>\n\n"},
+	}
+	for _, test := range tests {
+		options := dpk.TwitterOptions{LinkCache: cache, DiscoverEmbeds: true, EmbedPlayers: test.players}
+		if post, _ := convertTweet(t, entities, text, options); post != test.expected {
+			t.Errorf("Incorrect embed rendering. Got: '%s' Expected: '%s'", post, test.expected)
+		}
+	}
+}
+
+func TestOEmbedPhotos(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/oembed":
+			if r.URL.Query().Get("url") == "empty" {
+				fmt.Fprint(w, `{"type": "rich", "version": "1.0", "html": ""}`)
+				return
+			}
+			fmt.Fprint(w, `{"type": "photo", "version": "1.0", "title": "Sunset & sea", `+
+				`"url": "https://photos.example.com/1.jpg", "width": 800, "height": 600}`)
+		case "/empty":
+			fmt.Fprint(w, `<html><head><link rel="alternate" type="application/json+oembed" href="/oembed?url=empty"></head></html>`)
+		default:
+			fmt.Fprint(w, `<html><head><link rel="alternate" type="application/json+oembed" href="/oembed?url=photo"></head></html>`)
+		}
+	}))
+	defer server.Close()
+
+	entities := `{
+  "urls" : [ {
+    "url" : "https://t.co/AAAAAAAAAA",
+    "expanded_url" : "` + server.URL + `/photo",
+    "display_url" : "example.com/photo",
+    "indices" : [ "5", "28" ]
+  }, {
+    "url" : "https://t.co/BBBBBBBBBB",
+    "expanded_url" : "` + server.URL + `/empty",
+    "display_url" : "example.com/empty",
+    "indices" : [ "33", "56" ]
+  } ]
+}`
+	text := "See https://t.co/AAAAAAAAAA and https://t.co/BBBBBBBBBB"
+
+	// Photos are rendered from their URL, empty embeds are not cached
+	cache := &semweb.Cache{}
+	post, _ := convertTweet(t, entities, text, dpk.TwitterOptions{LinkCache: cache, DiscoverEmbeds: true})
+	expected := "See \n\n<img src=\"https://photos.example.com/1.jpg\" width=\"800\" height=\"600\" alt=\"Sunset &amp; sea\">\n\n" +
+		" and [example.com/empty](" + server.URL + "/empty)"
+	if post != expected {
+		t.Errorf("Incorrect photo rendering. Got: '%s' Expected: '%s'", post, expected)
+	}
+	if entry, ok := cache.Get(server.URL + "/oembed?url=empty"); ok {
+		t.Errorf("Empty embed should not be cached: %+v", entry)
+	}
+}
+
+func TestPreviewCards(t *testing.T) {
+	var img bytes.Buffer
+	if err := png.Encode(&img, image.NewRGBA(image.Rect(0, 0, 40, 20))); err != nil {
+		t.Errorf("Cannot encode image: %s", err)
+		return
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/article":
+			fmt.Fprint(w, `<html><head>
+<title>Article - Example</title>
+<meta property="og:title" content="ejabberd <3 XMPP">
+<meta property="og:description" content="A real-time messaging server.">
+<meta property="og:site_name" content="Example">
+<meta property="og:image" content="/cover.png">
+</head></html>`)
+		case "/cover.png":
+			w.Write(img.Bytes())
+		default:
+			fmt.Fprint(w, `<html><head></head></html>`)
+		}
+	}))
+	defer server.Close()
+
+	// Display URLs of pages without title are truncated to 50 characters
+	longPath := "/" + strings.Repeat("é", 60)
+	entities := `{
+  "urls" : [ {
+    "url" : "https://t.co/AAAAAAAAAA",
+    "expanded_url" : "` + server.URL + `/article",
+    "display_url" : "example.com/article",
+    "indices" : [ "5", "28" ]
+  }, {
+    "url" : "https://t.co/BBBBBBBBBB",
+    "expanded_url" : "` + server.URL + `/untitled",
+    "display_url" : "example.com` + longPath + `",
+    "indices" : [ "33", "56" ]
+  } ]
+}`
+	text := "Read https://t.co/AAAAAAAAAA and https://t.co/BBBBBBBBBB"
+
+	post, outputDir := convertTweet(t, entities, text, dpk.TwitterOptions{LinkCache: &semweb.Cache{}, PreviewCards: true})
+	postDir := filepath.Join(outputDir, tweetPostDir)
+	var metadata dpk.Metadata
+	data, err := ioutil.ReadFile(filepath.Join(postDir, "metadata.json"))
+	if err == nil {
+		err = json.Unmarshal(data, &metadata)
+	}
+	if err != nil || len(metadata.Media) != 1 {
+		t.Errorf("Missing preview image metadata: %v", err)
+		return
+	}
+
+	image := metadata.Media[0]
+	expected := "Read \n\n<div class=\"link-preview\">\n" +
+		"<a href=\"" + server.URL + "/article\"><img src=\"" + image.File + "\" alt=\"\"></a>\n" +
+		"<p><a href=\"" + server.URL + "/article\"><strong>ejabberd &lt;3 XMPP</strong></a></p>\n" +
+		"<p>A real-time messaging server.</p>\n" +
+		"<p><small>Example</small></p>\n" +
+		"</div>\n\n" +
+		" and [example.com/" + strings.Repeat("é", 38) + "…](" + server.URL + "/untitled)"
+	if post != expected {
+		t.Errorf("Incorrect preview card rendering. Got: '%s' Expected: '%s'", post, expected)
+	}
+	if image.Type != "thumbnail" || image.Width != 40 || image.Height != 20 || image.OriginalUrl != server.URL+"/cover.png" {
+		t.Errorf("Incorrect preview image metadata: %+v", image)
+	}
+	stored, err := ioutil.ReadFile(filepath.Join(postDir, image.File))
+	if err != nil || !bytes.Equal(stored, img.Bytes()) {
+		t.Errorf("Preview image not stored with post: %v", err)
+	}
+}
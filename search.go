@@ -0,0 +1,476 @@
+package dpk
+
+import (
+	"encoding/gob"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+//=============================================================================
+// Full-text search
+//
+// The search index is an inverted index, stored in the output directory and
+// generated from the post.md and metadata.json files of each post. For each
+// term, it lists the posts using it, with the positions of the term in the
+// post, to support phrase queries.
+
+// SearchIndexFile is the name of the full-text search index, in the output
+// directory.
+const SearchIndexFile = "dpk.search"
+
+type searchIndex struct {
+	Docs  []searchDoc
+	Terms map[string][]posting
+}
+
+// searchDoc is an indexed post.
+type searchDoc struct {
+	Path      string
+	Lang      string
+	Tags      []string
+	CreatedAt time.Time
+}
+
+// posting lists the positions of a term in a post.
+type posting struct {
+	Doc       int
+	Positions []int
+}
+
+// fieldGap separates the positions of the indexed fields of a post, so that
+// phrases do not match across fields.
+const fieldGap = 1000
+
+// BuildSearchIndex creates the full-text search index of the posts stored in
+// outputDir. An existing index is replaced.
+func BuildSearchIndex(outputDir string) error {
+	index := searchIndex{Terms: make(map[string][]posting)}
+	err := walkPosts(outputDir, func(postDir string, metadata Metadata) error {
+		content, err := readPostText(outputDir, postDir)
+		if err != nil {
+			return err
+		}
+		doc := len(index.Docs)
+		index.Docs = append(index.Docs, searchDoc{
+			Path:      postDir,
+			Lang:      metadata.Lang,
+			Tags:      metadata.HashTags,
+			CreatedAt: metadata.CreatedAt,
+		})
+
+		fields := []string{content}
+		for _, m := range metadata.Media {
+			fields = append(fields, m.AltText)
+		}
+		positions := make(map[string][]int)
+		for i, field := range fields {
+			for _, token := range tokenize(field, metadata.Lang) {
+				positions[token.term] = append(positions[token.term], i*fieldGap+token.position)
+			}
+		}
+		for term, p := range positions {
+			index.Terms[term] = append(index.Terms[term], posting{Doc: doc, Positions: p})
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	indexFile := filepath.Join(outputDir, SearchIndexFile)
+	tmpFile := indexFile + ".tmp"
+	f, err := os.Create(tmpFile)
+	if err != nil {
+		return err
+	}
+	if err = gob.NewEncoder(f).Encode(index); err != nil {
+		f.Close()
+		os.Remove(tmpFile)
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile, indexFile)
+}
+
+//=============================================================================
+// Queries
+
+// SearchOptions restricts the posts returned by a search.
+type SearchOptions struct {
+	// From and To restrict results to posts created in [From, To). Zero
+	// values do not restrict the range.
+	From time.Time
+	To   time.Time
+	// Tags restricts results to posts using all the tags.
+	Tags []string
+	// Limit is the maximum number of results. 0 means no limit.
+	Limit int
+}
+
+// SearchResult is a post matching a search query.
+type SearchResult struct {
+	// Path is the post directory, relative to the output directory.
+	Path      string
+	CreatedAt time.Time
+	// Snippet is an extract of the post text around the first match.
+	Snippet string
+	lang    string
+	score   int
+}
+
+// Search returns the posts stored in outputDir matching query, most relevant
+// first. The query is a list of terms and "quoted phrases", which must all be
+// found in the post.
+func Search(outputDir, query string, options SearchOptions) ([]SearchResult, error) {
+	if len(parseQuery(query, "")) == 0 {
+		return nil, fmt.Errorf("empty search query")
+	}
+	f, err := os.Open(filepath.Join(outputDir, SearchIndexFile))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var index searchIndex
+	if err = gob.NewDecoder(f).Decode(&index); err != nil {
+		return nil, fmt.Errorf("cannot read search index: %w", err)
+	}
+
+	// The query is tokenized for each language, like the posts in that language
+	langs := make(map[string]bool)
+	for _, doc := range index.Docs {
+		langs[doc.Lang] = true
+	}
+
+	var results []SearchResult
+	for lang := range langs {
+		phrases := parseQuery(query, lang)
+		if len(phrases) == 0 {
+			continue
+		}
+		for doc, score := range index.match(phrases) {
+			d := index.Docs[doc]
+			if d.Lang != lang || !options.accept(d) {
+				continue
+			}
+			results = append(results, SearchResult{Path: d.Path, CreatedAt: d.CreatedAt, lang: d.Lang, score: score})
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].score != results[j].score {
+			return results[i].score > results[j].score
+		}
+		return results[i].CreatedAt.After(results[j].CreatedAt)
+	})
+	if options.Limit > 0 && len(results) > options.Limit {
+		results = results[:options.Limit]
+	}
+
+	for i, r := range results {
+		text, err := readPostText(outputDir, r.Path)
+		if err != nil {
+			return nil, err
+		}
+		results[i].Snippet = snippet(text, r.lang, query)
+	}
+	return results, nil
+}
+
+var queryPhrase = regexp.MustCompile(`"([^"]*)"|[^\s"]+`)
+
+// parseQuery returns the phrases of the query, as lists of terms. Single terms
+// are phrases of one term.
+func parseQuery(query, lang string) [][]string {
+	var phrases [][]string
+	for _, m := range queryPhrase.FindAllStringSubmatch(query, -1) {
+		text := m[0]
+		if m[1] != "" {
+			text = m[1]
+		}
+		var phrase []string
+		for _, token := range tokenize(text, lang) {
+			phrase = append(phrase, token.term)
+		}
+		if len(phrase) > 0 {
+			phrases = append(phrases, phrase)
+		}
+	}
+	return phrases
+}
+
+// match returns the documents containing all the phrases, with the number of
+// matches as score.
+func (index searchIndex) match(phrases [][]string) map[int]int {
+	var scores map[int]int
+	for _, phrase := range phrases {
+		found := index.matchPhrase(phrase)
+		if scores == nil {
+			scores = found
+			continue
+		}
+		for doc := range scores {
+			if n, ok := found[doc]; ok {
+				scores[doc] += n
+			} else {
+				delete(scores, doc)
+			}
+		}
+	}
+	return scores
+}
+
+// matchPhrase returns the documents containing the terms of phrase at
+// consecutive positions, with the number of occurrences.
+func (index searchIndex) matchPhrase(phrase []string) map[int]int {
+	// Positions where the phrase can start, by document
+	starts := make(map[int]map[int]bool)
+	for _, p := range index.Terms[phrase[0]] {
+		starts[p.Doc] = make(map[int]bool)
+		for _, pos := range p.Positions {
+			starts[p.Doc][pos] = true
+		}
+	}
+	for offset, term := range phrase[1:] {
+		next := make(map[int]map[int]bool)
+		for _, p := range index.Terms[term] {
+			s, ok := starts[p.Doc]
+			if !ok {
+				continue
+			}
+			for _, pos := range p.Positions {
+				if start := pos - offset - 1; s[start] {
+					if next[p.Doc] == nil {
+						next[p.Doc] = make(map[int]bool)
+					}
+					next[p.Doc][start] = true
+				}
+			}
+		}
+		starts = next
+	}
+
+	found := make(map[int]int, len(starts))
+	for doc, s := range starts {
+		found[doc] = len(s)
+	}
+	return found
+}
+
+// accept returns true if the document matches the search filters.
+func (options SearchOptions) accept(doc searchDoc) bool {
+	if !options.From.IsZero() && doc.CreatedAt.Before(options.From) {
+		return false
+	}
+	if !options.To.IsZero() && !doc.CreatedAt.Before(options.To) {
+		return false
+	}
+	for _, tag := range options.Tags {
+		tag = strings.TrimPrefix(tag, "#")
+		found := false
+		for _, t := range doc.Tags {
+			if strings.EqualFold(t, tag) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+//=============================================================================
+// Text processing
+
+var (
+	markdownImage = regexp.MustCompile(`!\[[^\]]*\]\([^)]*\)`)
+	markdownLink  = regexp.MustCompile(`\[((?:[^\]\\]|\\.)*)\]\([^)]*\)`)
+	htmlTag       = regexp.MustCompile(`<[^>]+>`)
+	markdownEsc   = regexp.MustCompile(`\\(.)`)
+	markdownBlock = regexp.MustCompile(`(?m)^(?:>[ >]*|---$)`)
+)
+
+// readPostText returns the text of the post stored in postDir, without front
+// matter and Markdown markup.
+func readPostText(outputDir, postDir string) (string, error) {
+	data, err := ioutil.ReadFile(filepath.Join(outputDir, filepath.FromSlash(postDir), "post.md"))
+	if err != nil {
+		return "", err
+	}
+	return markdownToText(stripFrontMatter(string(data))), nil
+}
+
+// stripFrontMatter removes the YAML or TOML front matter of a post.
+func stripFrontMatter(content string) string {
+	for _, delimiter := range []string{"---\n", "+++\n"} {
+		if !strings.HasPrefix(content, delimiter) {
+			continue
+		}
+		if end := strings.Index(content[len(delimiter):], "\n"+delimiter); end >= 0 {
+			return content[len(delimiter)+end+len(delimiter)+1:]
+		}
+	}
+	return content
+}
+
+// markdownToText removes Markdown markup from post content: images, link
+// targets, HTML tags, quote markers, separators and escape characters.
+func markdownToText(markdown string) string {
+	text := markdownBlock.ReplaceAllString(markdown, "")
+	text = markdownImage.ReplaceAllString(text, "")
+	text = markdownLink.ReplaceAllString(text, "$1")
+	text = htmlTag.ReplaceAllString(text, "")
+	text = markdownEsc.ReplaceAllString(text, "$1")
+	return strings.TrimSpace(text)
+}
+
+// token is a normalized term, with its position in the text, counted in terms,
+// and its byte offsets.
+type token struct {
+	term       string
+	position   int
+	start, end int
+}
+
+// elisions are the words ending with an apostrophe in languages using elision,
+// like "l'" in French "l'archive". They are not indexed.
+var elisions = map[string][]string{
+	"fr": {"l", "d", "j", "m", "n", "s", "t", "c", "qu", "jusqu", "lorsqu", "puisqu", "quoiqu"},
+	"it": {"l", "d", "un", "dell", "all", "dall", "nell", "sull", "quest", "quell"},
+	"ca": {"l", "d", "s", "m", "n", "t"},
+}
+
+// tokenize splits text into lower case terms. Words are sequences of letters
+// and digits. Han, Hiragana, Katakana and Thai characters are indexed one by
+// one, as these scripts do not separate words with spaces. Apostrophes are
+// handled depending on lang: elided articles are dropped in French, Italian and
+// Catalan, and possessive "'s" is dropped in English.
+func tokenize(text, lang string) []token {
+	lang = strings.ToLower(strings.SplitN(lang, "-", 2)[0])
+	var tokens []token
+	add := func(term string, start, end int) {
+		tokens = append(tokens, token{term: term, position: len(tokens), start: start, end: end})
+	}
+
+	runes := []rune(text)
+	offsets := make([]int, len(runes)+1)
+	for i, offset := 0, 0; i < len(runes); i++ {
+		offsets[i] = offset
+		offset += len(string(runes[i]))
+		offsets[i+1] = offset
+	}
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case isCharacterScript(r):
+			add(string(unicode.ToLower(r)), offsets[i], offsets[i+1])
+			i++
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || unicode.IsMark(runes[i])) &&
+				!isCharacterScript(runes[i]) {
+				i++
+			}
+			word := strings.ToLower(string(runes[start:i]))
+			apostrophe := i+1 < len(runes) && isApostrophe(runes[i]) && unicode.IsLetter(runes[i+1])
+			switch {
+			case apostrophe && isElision(word, lang):
+				// Skip elided article and apostrophe
+				i++
+			case apostrophe && lang == "en" && unicode.ToLower(runes[i+1]) == 's' &&
+				(i+2 == len(runes) || !unicode.IsLetter(runes[i+2])):
+				// Drop possessive
+				add(word, offsets[start], offsets[i])
+				i += 2
+			default:
+				add(word, offsets[start], offsets[i])
+			}
+		default:
+			i++
+		}
+	}
+	return tokens
+}
+
+func isCharacterScript(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Thai)
+}
+
+func isApostrophe(r rune) bool {
+	return r == '\'' || r == '’'
+}
+
+func isElision(word, lang string) bool {
+	for _, e := range elisions[lang] {
+		if word == e {
+			return true
+		}
+	}
+	return false
+}
+
+// snippetLength is the approximate length of search result snippets, in bytes.
+const snippetLength = 120
+
+// snippet returns an extract of text around the first term of query found in
+// text.
+func snippet(text, lang, query string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if len(text) <= snippetLength {
+		return text
+	}
+	terms := make(map[string]bool)
+	for _, phrase := range parseQuery(query, lang) {
+		for _, term := range phrase {
+			terms[term] = true
+		}
+	}
+
+	start := 0
+	for _, t := range tokenize(text, lang) {
+		if terms[t.term] {
+			start = t.start
+			break
+		}
+	}
+	// Center snippet on the match, on word boundaries
+	from := start - snippetLength/3
+	if from <= 0 {
+		from = 0
+	} else if i := strings.IndexByte(text[from:start], ' '); i >= 0 {
+		from += i + 1
+	} else {
+		from = start
+	}
+	to := from + snippetLength
+	if to >= len(text) {
+		to = len(text)
+	} else if i := strings.LastIndexByte(text[start:to], ' '); i > 0 {
+		to = start + i
+	} else {
+		for to > start && !utf8.RuneStart(text[to]) {
+			to--
+		}
+	}
+
+	s := text[from:to]
+	if from > 0 {
+		s = "…" + s
+	}
+	if to < len(text) {
+		s += "…"
+	}
+	return s
+}
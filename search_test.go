@@ -0,0 +1,93 @@
+package dpk_test
+
+import (
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/processone/dpk"
+)
+
+func TestSearch(t *testing.T) {
+	archive := fstest.MapFS{
+		"tweet.js": &fstest.MapFile{Data: []byte(`window.YTD.tweet.part0 = [ {
+  "entities" : {
+    "hashtags" : [ {
+      "text" : "XMPP",
+      "indices" : [ "36", "41" ]
+    } ]
+  },
+  "id_str" : "1",
+  "created_at" : "Mon Jan 07 10:00:00 +0000 2019",
+  "full_text" : "Découvrez l'archive de nos messages #XMPP",
+  "lang" : "fr"
+}, {
+  "id_str" : "2",
+  "created_at" : "Tue Jan 08 10:00:00 +0000 2019",
+  "full_text" : "ProcessOne's archive is now online. Find old messages in the archive!",
+  "lang" : "en"
+}, {
+  "id_str" : "3",
+  "created_at" : "Wed Jan 09 10:00:00 +0000 2019",
+  "full_text" : "東京でXMPPの会議",
+  "lang" : "ja"
+} ]`)},
+	}
+	outputDir := t.TempDir()
+	if err := dpk.TwitterArchiveToMD(archive, outputDir, dpk.TwitterOptions{}); err != nil {
+		t.Errorf("Cannot convert archive: %s", err)
+		return
+	}
+
+	tests := []struct {
+		query    string
+		options  dpk.SearchOptions
+		expected []string
+	}{
+		// Elided article in French and possessive in English are not part of the terms
+		{query: "archive", expected: []string{"2019/01/08/001", "2019/01/07/001"}},
+		{query: "processone", expected: []string{"2019/01/08/001"}},
+		{query: `"old messages"`, expected: []string{"2019/01/08/001"}},
+		{query: `"messages old"`},
+		{query: "archive messages", expected: []string{"2019/01/08/001", "2019/01/07/001"}},
+		{query: "東京", expected: []string{"2019/01/09/001"}},
+		{query: "xmpp", expected: []string{"2019/01/09/001", "2019/01/07/001"}},
+		{query: "xmpp", options: dpk.SearchOptions{Tags: []string{"#xmpp"}}, expected: []string{"2019/01/07/001"}},
+		{query: "archive", options: dpk.SearchOptions{From: time.Date(2019, 1, 8, 0, 0, 0, 0, time.UTC)},
+			expected: []string{"2019/01/08/001"}},
+		{query: "archive", options: dpk.SearchOptions{To: time.Date(2019, 1, 8, 0, 0, 0, 0, time.UTC)},
+			expected: []string{"2019/01/07/001"}},
+		{query: "archive", options: dpk.SearchOptions{Limit: 1}, expected: []string{"2019/01/08/001"}},
+	}
+
+	for _, tc := range tests {
+		results, err := dpk.Search(outputDir, tc.query, tc.options)
+		if err != nil {
+			t.Errorf("%s: search failed: %s", tc.query, err)
+			continue
+		}
+		var paths []string
+		for _, r := range results {
+			paths = append(paths, r.Path)
+		}
+		if len(paths) != len(tc.expected) {
+			t.Errorf("%s: incorrect results. Got: %v Expected: %v", tc.query, paths, tc.expected)
+			continue
+		}
+		for i := range paths {
+			if paths[i] != tc.expected[i] {
+				t.Errorf("%s: incorrect results. Got: %v Expected: %v", tc.query, paths, tc.expected)
+				break
+			}
+		}
+	}
+
+	results, err := dpk.Search(outputDir, `"old messages"`, dpk.SearchOptions{})
+	if err != nil || len(results) != 1 {
+		t.Errorf("Phrase search failed: %v %s", results, err)
+		return
+	}
+	if expected := "ProcessOne's archive is now online. Find old messages in the archive!"; results[0].Snippet != expected {
+		t.Errorf("Incorrect snippet. Got: '%s' Expected: '%s'", results[0].Snippet, expected)
+	}
+}
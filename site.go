@@ -0,0 +1,132 @@
+package dpk
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+//=============================================================================
+// Static site generators
+//
+// Hugo and Jekyll exporters write posts as content for the site generator, with
+// a front matter generated from the post metadata. Hashtags are listed in the
+// front matter, and links to the DPK tag index pages are replaced with links to
+// the site taxonomy pages.
+
+func init() {
+	RegisterExporter("hugo", HugoExporter{})
+	RegisterExporter("jekyll", JekyllExporter{})
+}
+
+// HugoExporter stores each post as a Hugo page bundle, in
+// content/<Section>/YYYY/MM/DD/NNN/index.md, with its attachments. Posts
+// without section are stored in the posts section.
+type HugoExporter struct {
+	// FrontMatter is the format of the page front matter. Defaults to YAML.
+	FrontMatter FrontMatter
+}
+
+// Export writes posts to outputDir as a Hugo content tree.
+func (e HugoExporter) Export(outputDir string, posts []Post) error {
+	format := e.FrontMatter
+	if format == NoFrontMatter {
+		format = YAMLFrontMatter
+	}
+	for _, p := range numberPosts(posts) {
+		section := p.Section
+		if section == "" {
+			section = "posts"
+		}
+		targetDir := filepath.Join(outputDir, "content", section, p.dayDir(), p.number)
+		if err := os.MkdirAll(targetDir, 0755); err != nil {
+			return err
+		}
+		writeAttachments(p.Post, targetDir)
+
+		frontMatter, err := format.render(p.Metadata())
+		if err != nil {
+			return err
+		}
+		content := frontMatter + siteTagLinks(p.Content)
+		if err = ioutil.WriteFile(filepath.Join(targetDir, "index.md"), []byte(content), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// JekyllExporter stores each post in the _posts directory, as
+// YYYY-MM-DD-<Id>.md. As Jekyll posts are single files, attachments are stored
+// in assets/<Provider>/<Id>/. The post section is used as Jekyll category.
+type JekyllExporter struct{}
+
+// jekyllFrontMatter adds Jekyll specific fields to the post metadata.
+type jekyllFrontMatter struct {
+	Layout     string   `yaml:"layout"`
+	Categories []string `yaml:"categories,omitempty"`
+	Metadata   `yaml:",inline"`
+}
+
+// Export writes posts to outputDir as a Jekyll site.
+func (JekyllExporter) Export(outputDir string, posts []Post) error {
+	postsDir := filepath.Join(outputDir, "_posts")
+	if err := os.MkdirAll(postsDir, 0755); err != nil {
+		return err
+	}
+	for _, post := range posts {
+		body := post.Content
+		if len(post.Attachments) > 0 {
+			assetsDir := path.Join("assets", post.Provider, post.Id)
+			if err := os.MkdirAll(filepath.Join(outputDir, filepath.FromSlash(assetsDir)), 0755); err != nil {
+				return err
+			}
+			writeAttachments(post, filepath.Join(outputDir, filepath.FromSlash(assetsDir)))
+			body = attachmentLinks(body, post.Attachments, "/"+assetsDir)
+		}
+
+		metadata := jekyllFrontMatter{Layout: "post", Metadata: post.Metadata()}
+		if post.Section != "" {
+			metadata.Categories = []string{post.Section}
+		}
+		frontMatter, err := YAMLFrontMatter.render(metadata)
+		if err != nil {
+			return err
+		}
+		content := frontMatter + siteTagLinks(body)
+
+		filename := fmt.Sprintf("%s-%s.md", post.CreatedAt.Format("2006-01-02"), post.Id)
+		if err = ioutil.WriteFile(filepath.Join(postsDir, filename), []byte(content), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//=============================================================================
+// Links rewriting
+
+var tagPageLink = regexp.MustCompile(`\]\(/tags/([^)\s]+)\.md\)`)
+
+// siteTagLinks replaces links to DPK tag index pages with links to the tags
+// taxonomy pages of the site.
+func siteTagLinks(content string) string {
+	return tagPageLink.ReplaceAllString(content, "](/tags/$1/)")
+}
+
+// attachmentLinks replaces references to attachments, stored next to the post,
+// with references to the attachments in dir.
+func attachmentLinks(content string, attachments []Attachment, dir string) string {
+	var replacements []string
+	for _, a := range attachments {
+		target := path.Join(dir, a.Filename)
+		replacements = append(replacements,
+			"("+a.Filename+")", "("+target+")",
+			`"`+a.Filename+`"`, `"`+target+`"`)
+	}
+	return strings.NewReplacer(replacements...).Replace(content)
+}
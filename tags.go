@@ -0,0 +1,104 @@
+package dpk
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+//=============================================================================
+// Hashtags index
+//
+// Hashtags link to a local index page for the tag, in the tags/ directory of
+// the output, listing all the posts using it. Links are relative to the root of
+// the output directory.
+
+// tagIndex gathers the posts using each hashtag, by lower case tag.
+type tagIndex map[string][]taggedPost
+
+type taggedPost struct {
+	tag       string
+	createdAt time.Time
+	postPath  string
+}
+
+// tagLink returns the path of the index page for tag.
+func tagLink(tag string) string {
+	return path.Join("/tags", strings.ToLower(tag)+".md")
+}
+
+// add references the post stored in postDir, relative to the output directory,
+// in the index pages of tags.
+func (t tagIndex) add(tags []string, createdAt time.Time, postDir string) {
+	for _, tag := range tags {
+		key := strings.ToLower(tag)
+		t[key] = append(t[key], taggedPost{
+			tag:       tag,
+			createdAt: createdAt,
+			postPath:  path.Join("/", filepath.ToSlash(postDir), "post.md"),
+		})
+	}
+}
+
+// writeTagIndex generates the index pages for the tags of all the posts stored
+// in outputDir.
+func writeTagIndex(outputDir string) error {
+	tags := make(tagIndex)
+	err := walkPosts(outputDir, func(postDir string, metadata Metadata) error {
+		tags.add(metadata.HashTags, metadata.CreatedAt, postDir)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return tags.write(outputDir)
+}
+
+// tagListPage is the name of the page listing all tags. It contains a hyphen,
+// which hashtags cannot, so that it never replaces the index page of a tag.
+const tagListPage = "all-tags.md"
+
+// write generates an index page for each tag, as well as a page listing all
+// tags, in the tags/ directory of outputDir. Existing pages are replaced.
+func (t tagIndex) write(outputDir string) error {
+	tagsDir := filepath.Join(outputDir, "tags")
+	if err := os.RemoveAll(tagsDir); err != nil {
+		return err
+	}
+	if len(t) == 0 {
+		return nil
+	}
+	if err := os.MkdirAll(tagsDir, 0755); err != nil {
+		return err
+	}
+
+	var keys []string
+	for key := range t {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var index strings.Builder
+	index.WriteString("# Tags\n\n")
+	for _, key := range keys {
+		posts := t[key]
+		sort.SliceStable(posts, func(i, j int) bool { return posts[i].createdAt.Before(posts[j].createdAt) })
+		tag := escapeMarkdown("#"+posts[0].tag, false)
+
+		var page strings.Builder
+		page.WriteString(fmt.Sprintf("# %s\n\n", tag))
+		for _, post := range posts {
+			page.WriteString(fmt.Sprintf("- [%s](%s)\n", post.createdAt.Format("2 Jan 2006 15:04"), post.postPath))
+		}
+		if err := ioutil.WriteFile(filepath.Join(tagsDir, key+".md"), []byte(page.String()), 0644); err != nil {
+			return err
+		}
+		index.WriteString(fmt.Sprintf("- [%s](%s) (%d)\n", tag, tagLink(key), len(posts)))
+	}
+	return ioutil.WriteFile(filepath.Join(tagsDir, tagListPage), []byte(index.String()), 0644)
+}
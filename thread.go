@@ -0,0 +1,80 @@
+package dpk
+
+import (
+	"strings"
+)
+
+//=============================================================================
+// Reply chains
+
+// Thread is a chain of tweets where the author replies to their own tweets.
+// A standalone tweet is a thread containing a single tweet.
+type Thread []Tweet
+
+// ReplyPolicy defines how replies to other users are converted.
+type ReplyPolicy int
+
+const (
+	// SkipReplies ignores replies to other users. This is the default.
+	SkipReplies ReplyPolicy = iota
+	// KeepReplies converts replies to other users along other posts, with type "reply".
+	KeepReplies
+	// SeparateReplies converts replies to other users in a separate replies/ directory tree.
+	SeparateReplies
+)
+
+// buildThreads links tweets into reply chains and returns the list of threads.
+// Tweets are expected to be sorted by creation date: a self-reply is appended to
+// the thread of the tweet it replies to, as long as this tweet is in the archive.
+// As the archive only holds the tweets of its owner, a reply to a tweet of the
+// archive is a self-reply, even when the screen name of the owner is unknown.
+func buildThreads(tweets Tweets) []Thread {
+	var threads []Thread
+	// Index of the thread each tweet belongs to, by tweet ID
+	threadIndex := make(map[string]int)
+
+	for _, tweet := range tweets {
+		if tweet.ReplyToTweetId != "" {
+			if i, ok := threadIndex[tweet.ReplyToTweetId]; ok {
+				threads[i] = append(threads[i], tweet)
+				threadIndex[tweet.Id] = i
+				continue
+			}
+		}
+		threadIndex[tweet.Id] = len(threads)
+		threads = append(threads, Thread{tweet})
+	}
+	return threads
+}
+
+// isSelfReply checks if the tweet is a reply to a tweet from the same author.
+func isSelfReply(tweet Tweet, screenName string) bool {
+	return tweet.ReplyToTweetId != "" && screenName != "" &&
+		strings.EqualFold(tweet.ReplyToUser, screenName)
+}
+
+// inReplyToUrl returns the URL of the tweet this tweet replies to, or an empty
+// string if it is not a reply.
+func inReplyToUrl(tweet Tweet) string {
+	if tweet.ReplyToTweetId == "" {
+		return ""
+	}
+	return tweetUrl(tweet.ReplyToUser, tweet.ReplyToTweetId)
+}
+
+// postTags returns the list of hashtags used in a thread, without duplicates.
+func postTags(thread Thread) []string {
+	var tags []string
+	seen := make(map[string]bool)
+	for _, tweet := range thread {
+		for _, h := range tweet.Entities.HashTags {
+			key := strings.ToLower(h.Text)
+			if h.Text == "" || seen[key] {
+				continue
+			}
+			seen[key] = true
+			tags = append(tags, h.Text)
+		}
+	}
+	return tags
+}
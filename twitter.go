@@ -1,18 +1,22 @@
 package dpk
 
 import (
-	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"html"
 	"io"
+	"io/fs"
 	"io/ioutil"
-	"net"
-	"net/http"
 	"net/url"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/microcosm-cc/bluemonday"
@@ -33,6 +37,7 @@ type Url struct {
 type Mention struct {
 	Name       string
 	ScreenName string `json:"screen_name"`
+	Indices    []string
 }
 
 type HashTag struct {
@@ -48,8 +53,9 @@ type Symbol struct {
 type Entities struct {
 	HashTags     []HashTag
 	Symbols      []Symbol
-	UserMentions []Mention
+	UserMentions []Mention `json:"user_mentions"`
 	Urls         []Url
+	Media        []Media
 }
 
 type Variant struct {
@@ -73,20 +79,42 @@ type VideoInfo struct {
 // Media filename is TweetID-FilePartOfTheMediaUrl
 type Media struct {
 	// Type can be "photo", "animated_gif", "video"
-	Type      string
-	Url       string
-	MediaUrl  string    `json:"media_url"`
-	VideoInfo VideoInfo `json:"video_info"`
+	Type          string
+	Url           string
+	MediaUrl      string    `json:"media_url"`
+	MediaUrlHttps string    `json:"media_url_https"`
+	VideoInfo     VideoInfo `json:"video_info"`
+	Indices       []string
+	Sizes         MediaSizes
+	AltText       string `json:"ext_alt_text"`
+}
+
+// MediaSizes lists the sizes in which the media is available. Media files in
+// the archive are the large size.
+type MediaSizes struct {
+	Large MediaSize
+}
+
+type MediaSize struct {
+	Width  string `json:"w"`
+	Height string `json:"h"`
 }
 
 type ExtendedEntities struct {
 	Media []Media
 }
 
+type User struct {
+	Name       string
+	ScreenName string `json:"screen_name"`
+}
+
 type Tweet struct {
-	Id               string `json:"id_str"`
-	FullText         string `json:"full_text"`
+	Id               string   `json:"id_str"`
+	FullText         string   `json:"full_text"`
+	DisplayTextRange []string `json:"display_text_range"`
 	Lang             string
+	User             *User
 	Retweeted        bool
 	FavoriteCount    string `json:"favorite_count"`
 	RetweetCount     string `json:"retweet_count"`
@@ -97,9 +125,19 @@ type Tweet struct {
 	Entities         Entities
 	ExtendedEntities ExtendedEntities `json:"extended_entities"`
 	Truncated        bool
+	RetweetedStatus  *Tweet `json:"retweeted_status"`
+	QuotedStatusId   string `json:"quoted_status_id_str"`
+	QuotedStatus     *Tweet `json:"quoted_status"`
 	Timestamp        time.Time
 }
 
+// Account is the archive owner account information.
+type Account struct {
+	Username    string
+	AccountId   string `json:"accountId"`
+	DisplayName string `json:"accountDisplayName"`
+}
+
 // Implements Sorter interface
 type Tweets []Tweet
 
@@ -107,45 +145,156 @@ func (t Tweets) Len() int           { return len(t) }
 func (t Tweets) Swap(i, j int)      { t[i], t[j] = t[j], t[i] }
 func (t Tweets) Less(i, j int) bool { return t[i].Timestamp.Before(t[j].Timestamp) }
 
-//=============================================================================
-// Post metadata struct for marshaling
-
-type Metadata struct {
-	Type      string
-	Lang      string
-	HashTags  []HashTag `json:",omitempty"`
-	CreatedAt time.Time
-}
-
 type localMedia struct {
 	mediaType   string
 	filename    string
 	originalUrl string
+	width       int
+	height      int
+	altText     string
+}
+
+// twitterConverter holds the data shared by the conversion of all the tweets
+// of an archive.
+type twitterConverter struct {
+	archive fs.FS
+	layout  twitterLayout
+	account Account
+	// client resolves links, with the link cache
+	client         semweb.Client
+	rewriter       *semweb.Rewriter
+	youtubeFacade  bool
+	discoverEmbeds bool
+	embedPlayers   bool
+	previewCards   bool
+	// Tweets from the archive, by ID
+	tweets map[string]Tweet
+	// Links resolved before rendering, by URL
+	resolved map[string]linkTarget
 }
 
 //=============================================================================
 // Data conversion
 
-func TwitterToMD(archiveDir, OutputDir string) error {
-	// =================================
-	// Read Tweets
-	data, err := ioutil.ReadFile(filepath.Join(archiveDir, "tweet.js"))
+// TwitterOptions controls how a Twitter archive is converted.
+type TwitterOptions struct {
+	// ScreenName is the Twitter handle of the archive owner, used to detect
+	// self-replies. When empty, it is read from the archive account data.
+	ScreenName string
+	// Replies defines how replies to other users are converted.
+	Replies ReplyPolicy
+	// Exporter writes the converted posts. Defaults to DPK layout.
+	Exporter Exporter
+	// LinkCache stores the resolution of links across runs. It is optional.
+	LinkCache *semweb.Cache
+	// CanonicalLinks replaces resolved short URLs with the canonical URL of
+	// their target page, when it declares one.
+	CanonicalLinks bool
+	// Rewriter fixes links before they are resolved. Defaults to the default
+	// rewrite rules.
+	Rewriter *semweb.Rewriter
+	// DiscoverEmbeds looks for the oEmbed endpoint of links not supported by
+	// known providers in the page they point to. It requires retrieving all
+	// linked pages.
+	DiscoverEmbeds bool
+	// EmbedPlayers keeps the players of embedded content, in iframes, which
+	// load third-party content as soon as the post is displayed. By default,
+	// players are removed and links with nothing else to embed are rendered
+	// as plain links or preview cards.
+	EmbedPlayers bool
+	// PreviewCards renders links as preview cards, with the title,
+	// description and image of the target page. It requires retrieving all
+	// linked pages.
+	PreviewCards bool
+	// YouTubeFacade renders links to YouTube videos with a player loaded from
+	// youtube-nocookie.com when their thumbnail is clicked.
+	YouTubeFacade bool
+}
+
+// TwitterToMD converts the Twitter archive stored at archivePath to a Markdown
+// directory structure in OutputDir. archivePath can be the archive ZIP file, as
+// downloaded from Twitter, or the directory where it has been unzipped.
+func TwitterToMD(archivePath, OutputDir string, options TwitterOptions) error {
+	archive, err := OpenArchive(archivePath)
 	if err != nil {
 		return err
 	}
+	defer archive.Close()
+
+	return TwitterArchiveToMD(archive, OutputDir, options)
+}
 
-	var tweets Tweets
-	jsonData := bytes.TrimPrefix(data, []byte("window.YTD.tweet.part0 = "))
-	if err = json.Unmarshal(jsonData, &tweets); err != nil {
+// TwitterArchiveToMD converts the Twitter archive available from filesystem
+// archive to a Markdown directory structure in OutputDir, using the exporter
+// from options.
+func TwitterArchiveToMD(archive fs.FS, OutputDir string, options TwitterOptions) error {
+	importer := TwitterImporter{Options: options}
+	posts, err := importer.Import(archive)
+	if err != nil {
+		return err
+	}
+	exporter := options.Exporter
+	if exporter == nil {
+		exporter = DPKExporter{}
+	}
+	if err = exporter.Export(OutputDir, posts); err != nil {
 		return err
 	}
+	return writeSkippedReport(OutputDir, importer.Skipped)
+}
+
+//=============================================================================
+// Twitter importer
+
+// TwitterImporter converts a Twitter archive to posts.
+type TwitterImporter struct {
+	Options TwitterOptions
+	// Skipped lists the tweets that could not be converted by the last import.
+	Skipped []SkippedTweet
+}
+
+func init() {
+	RegisterImporter("twitter", func() Importer { return &TwitterImporter{} })
+}
+
+// Import converts the tweets from a Twitter archive to posts.
+func (importer *TwitterImporter) Import(archive fs.FS) ([]Post, error) {
+	importer.Skipped = nil
+	options := importer.Options
+
+	// =================================
+	// Read Tweets
+	layout, err := detectTwitterLayout(archive)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ReadArchiveData(archive, layout.dataDir, layout.tweets)
+	if err != nil {
+		return nil, err
+	}
+
+	tweets := make(Tweets, len(data))
+	for i, element := range data {
+		if tweets[i], err = decodeTweet(element); err != nil {
+			return nil, err
+		}
+	}
+
+	account, err := readAccount(archive, layout)
+	if err != nil {
+		return nil, err
+	}
+	if options.ScreenName != "" {
+		account.Username = options.ScreenName
+	}
+	screenName := account.Username
 
 	// =================================
 	// Parse the date for all tweets
 	for i, tweet := range tweets {
 		tweets[i].Timestamp, err = rubyDateToTime(tweet.CreatedAt)
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
 
@@ -153,96 +302,302 @@ func TwitterToMD(archiveDir, OutputDir string) error {
 	// Sort tweets by creation date
 	sort.Sort(tweets)
 
+	converter := twitterConverter{
+		archive:  archive,
+		layout:   layout,
+		account:  account,
+		client:   semweb.NewClient(),
+		tweets:   make(map[string]Tweet, len(tweets)),
+		resolved: make(map[string]linkTarget),
+	}
+
+	converter.client.Cache = options.LinkCache
+	converter.client.PreferCanonical = options.CanonicalLinks
+	converter.youtubeFacade = options.YouTubeFacade
+	converter.discoverEmbeds = options.DiscoverEmbeds
+	converter.embedPlayers = options.EmbedPlayers
+	converter.previewCards = options.PreviewCards
+	if converter.rewriter = options.Rewriter; converter.rewriter == nil {
+		converter.rewriter = semweb.DefaultRewriter()
+	}
+
 	// =================================
-	// Convert each tweet to Markdown and prepare a directory structure for it
-	index := 1
-	currentDir := ""
-	for _, tweet := range tweets {
-		if isReply(tweet) {
-			continue
+	// Restore the content of truncated tweets, when possible
+	lost := make(map[string]string)
+	for i := range tweets {
+		if reason := recoverTruncated(&tweets[i]); reason != "" {
+			lost[tweets[i].Id] = reason
 		}
+		converter.tweets[tweets[i].Id] = tweets[i]
+	}
 
-		if isTruncated(tweet) {
+	// =================================
+	// Select the threads to convert
+	var threads []Thread
+	for _, thread := range buildThreads(tweets) {
+		if options.Replies == SkipReplies && isReply(thread[0], screenName) {
 			continue
 		}
 
-		year := tweet.Timestamp.Year()
-		month := tweet.Timestamp.Month()
-		day := tweet.Timestamp.Day()
-		newDir := filepath.Join(
-			fmt.Sprintf("%02d", year),
-			fmt.Sprintf("%02d", month),
-			fmt.Sprintf("%02d", day))
-		if newDir == currentDir {
-			index++
-		} else {
-			currentDir = newDir
-			index = 1
+		// Tweets whose content cannot be recovered are reported instead of being converted
+		var kept Thread
+		for _, t := range thread {
+			if reason, ok := lost[t.Id]; ok {
+				importer.Skipped = append(importer.Skipped, SkippedTweet{
+					Id:        t.Id,
+					Url:       tweetUrl(screenName, t.Id),
+					Reason:    reason,
+					Text:      t.FullText,
+					CreatedAt: t.Timestamp,
+				})
+				continue
+			}
+			kept = append(kept, t)
+		}
+		if len(kept) > 0 {
+			threads = append(threads, kept)
 		}
+	}
 
-		// Create directory for post
-		targetDir := filepath.Join(OutputDir, newDir, fmt.Sprintf("%03d", index))
-		if err = os.MkdirAll(targetDir, 0755); err != nil {
-			return err
+	// =================================
+	// Resolve links from all threads
+	converter.resolveLinks(threads)
+
+	// =================================
+	// Convert each thread to a post
+	var posts []Post
+	for _, thread := range threads {
+		post := converter.threadToPost(thread)
+		if isReply(thread[0], screenName) {
+			post.Type = "reply"
+			if options.Replies == SeparateReplies {
+				post.Section = "replies"
+			}
+			if post.InReplyTo = inReplyToUrl(thread[0]); post.InReplyTo != "" {
+				label := post.InReplyTo
+				if thread[0].ReplyToUser != "" {
+					label = "@" + thread[0].ReplyToUser
+				}
+				post.Content = fmt.Sprintf("In reply to [%s](%s)\n\n", label, post.InReplyTo) + post.Content
+			}
 		}
-		// Copy media
-		mediafiles := getMedia(tweet)
-		for _, mediafile := range mediafiles {
-			err = copyFile(
-				filepath.Join(archiveDir, "tweet_media", mediafile.filename),
-				filepath.Join(targetDir, mediafile.filename))
-			if err != nil {
-				fmt.Println("Error copying", mediafile.filename)
+		posts = append(posts, post)
+	}
+
+	return posts, nil
+}
+
+// threadToPost converts a thread to a post, with each tweet of the thread as a
+// section of the post.
+func (c twitterConverter) threadToPost(thread Thread) Post {
+	tweet := thread[0]
+	post := Post{
+		Id:       tweet.Id,
+		Provider: "twitter",
+		Type:     tweetType(tweet),
+		Author: Author{
+			Name:   c.account.DisplayName,
+			Handle: c.account.Username,
+		},
+		Lang:      tweet.Lang,
+		Tags:      postTags(thread),
+		SourceUrl: tweetUrl(c.account.Username, tweet.Id),
+		Client:    parseClientLink(tweet.ClientLink),
+		CreatedAt: tweet.Timestamp,
+		UpdatedAt: thread[len(thread)-1].Timestamp,
+	}
+	if c.account.Username != "" {
+		post.Author.Url = "https://twitter.com/" + c.account.Username
+	}
+	// Counts are the ones of the first tweet, whose permalink is the post source
+	post.FavoriteCount, _ = strconv.Atoi(tweet.FavoriteCount)
+	post.RepostCount, _ = strconv.Atoi(tweet.RetweetCount)
+
+	var sections []string
+	for _, t := range thread {
+		mediafiles := c.addMedia(t, &post)
+		sections = append(sections, c.tweetToMd(t, &post, mediafiles, true))
+		// Tweet permalink path, so that links to the tweets can be redirected to the post
+		post.Aliases = append(post.Aliases, strings.TrimPrefix(tweetUrl(c.account.Username, t.Id), "https://twitter.com"))
+		for _, m := range t.Entities.UserMentions {
+			if strings.EqualFold(m.ScreenName, c.account.Username) {
+				// Self-mentions added when replying to own tweets
+				continue
 			}
+			post.addMention(Author{
+				Name:   m.Name,
+				Handle: m.ScreenName,
+				Url:    "https://twitter.com/" + m.ScreenName,
+			})
 		}
-		// Generate markdown for post
-		if err = ioutil.WriteFile(filepath.Join(targetDir, "post.md"), []byte(tweetToMd(tweet, targetDir, mediafiles)), 0644); err != nil {
-			return err
+	}
+	post.Content = strings.Join(sections, "\n\n---\n\n")
+	return post
+}
+
+//=============================================================================
+// Archive layouts
+
+// twitterLayout describes where tweets and media files are stored in a Twitter archive.
+type twitterLayout struct {
+	dataDir  string // Directory containing the Javascript data files
+	tweets   string // Data type name of the tweets files
+	mediaDir string // Directory containing tweets media files
+}
+
+// twitterLayouts lists the known archive layouts, from the most recent to the oldest:
+//   - Current archives put everything under data/, in tweets.js and tweets_media/.
+//   - 2019 archives moved data files under data/, but kept the tweet.js name.
+//   - 2018 archives have tweet.js and tweet_media/ at the root of the archive.
+var twitterLayouts = []twitterLayout{
+	{dataDir: "data", tweets: "tweets", mediaDir: "data/tweets_media"},
+	{dataDir: "data", tweets: "tweet", mediaDir: "data/tweet_media"},
+	{dataDir: ".", tweets: "tweet", mediaDir: "tweet_media"},
+}
+
+// detectTwitterLayout returns the layout used by the archive.
+func detectTwitterLayout(archive fs.FS) (twitterLayout, error) {
+	for _, layout := range twitterLayouts {
+		parts, err := findArchiveParts(archive, layout.dataDir, layout.tweets)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return layout, err
 		}
-		// Generate Metadata file
-		metadata := Metadata{
-			Type:      "microblog",
-			Lang:      tweet.Lang,
-			CreatedAt: tweet.Timestamp,
+		if len(parts) > 0 {
+			return layout, nil
 		}
-		meta, err := json.Marshal(metadata)
+	}
+
+	// Archives that have been unzipped and compressed again may have all their
+	// content in a single top-level directory.
+	entries, err := fs.ReadDir(archive, ".")
+	if err == nil && len(entries) == 1 && entries[0].IsDir() {
+		root := entries[0].Name()
+		sub, err := fs.Sub(archive, root)
 		if err != nil {
-			return err
+			return twitterLayout{}, err
 		}
-		if err = ioutil.WriteFile(filepath.Join(targetDir, "metadata.json"), meta, 0644); err != nil {
-			return err
+		if layout, err := detectTwitterLayout(sub); err == nil {
+			layout.dataDir = path.Join(root, layout.dataDir)
+			layout.mediaDir = path.Join(root, layout.mediaDir)
+			return layout, nil
+		}
+	}
+	return twitterLayout{}, errors.New("this does not look like a Twitter archive: cannot find tweets data")
+}
+
+// readAccount returns the archive owner account. It returns an empty account if
+// the archive does not contain account data.
+func readAccount(archive fs.FS, layout twitterLayout) (Account, error) {
+	var account Account
+	data, err := ReadArchiveData(archive, layout.dataDir, "account")
+	if errors.Is(err, fs.ErrNotExist) || len(data) == 0 {
+		return account, nil
+	}
+	if err != nil {
+		return account, err
+	}
+
+	var wrapper struct {
+		Account *Account `json:"account"`
+	}
+	if err = json.Unmarshal(data[0], &wrapper); err != nil {
+		return account, err
+	}
+	if wrapper.Account != nil {
+		return *wrapper.Account, nil
+	}
+	err = json.Unmarshal(data[0], &account)
+	return account, err
+}
+
+// decodeTweet parses a tweet from an archive data file. Recent archives wrap
+// each tweet in an object: {"tweet": {...}}, while older ones store it directly.
+func decodeTweet(element json.RawMessage) (Tweet, error) {
+	var wrapper struct {
+		Tweet *Tweet `json:"tweet"`
+	}
+	if err := json.Unmarshal(element, &wrapper); err != nil {
+		return Tweet{}, err
+	}
+	if wrapper.Tweet != nil {
+		return *wrapper.Tweet, nil
+	}
+
+	var tweet Tweet
+	err := json.Unmarshal(element, &tweet)
+	return tweet, err
+}
+
+//=============================================================================
+// Tweet conversion helpers
+
+// addMedia attaches the media files of the tweet to the post.
+func (c twitterConverter) addMedia(tweet Tweet, post *Post) []localMedia {
+	mediafiles := getMedia(tweet)
+	for _, mediafile := range mediafiles {
+		if hasAttachment(*post, mediafile.filename) {
+			continue
 		}
+		archive := c.archive
+		name := path.Join(c.layout.mediaDir, mediafile.filename)
+		post.Attachments = append(post.Attachments, Attachment{
+			Filename:    mediafile.filename,
+			Type:        mediafile.mediaType,
+			OriginalUrl: mediafile.originalUrl,
+			Width:       mediafile.width,
+			Height:      mediafile.height,
+			AltText:     mediafile.altText,
+			Open: func() (io.ReadCloser, error) {
+				return archive.Open(name)
+			},
+		})
 	}
+	return mediafiles
+}
 
-	return nil
+func hasAttachment(post Post, filename string) bool {
+	for _, a := range post.Attachments {
+		if a.Filename == filename {
+			return true
+		}
+	}
+	return false
 }
 
 func getMedia(tweet Tweet) []localMedia {
 	var files []localMedia
 	for _, media := range tweet.ExtendedEntities.Media {
-		mediafile := ""
+		mediaUrl := ""
 		switch media.Type {
 		case "photo":
-			mediafile = baseName(media.MediaUrl)
+			mediaUrl = media.MediaUrl
+			if media.MediaUrlHttps != "" {
+				mediaUrl = media.MediaUrlHttps
+			}
 		case "animated_gif":
 			variants := media.VideoInfo.Variants
 			if len(variants) > 0 {
 				sort.Sort(variants)
-				mediafile = baseName(variants[0].Url)
+				mediaUrl = variants[0].Url
 			}
 		case "video":
 			variants := media.VideoInfo.Variants
 			if len(variants) > 0 {
 				sort.Sort(variants)
-				mediafile = baseName(variants[0].Url)
+				mediaUrl = variants[0].Url
 			}
 		}
-		if mediafile != "" {
-			filename := fmt.Sprintf("%s-%s", tweet.Id, mediafile)
+		if mediaUrl != "" {
+			filename := fmt.Sprintf("%s-%s", tweet.Id, baseName(mediaUrl))
+			width, _ := strconv.Atoi(media.Sizes.Large.Width)
+			height, _ := strconv.Atoi(media.Sizes.Large.Height)
 			files = append(files, localMedia{
 				mediaType:   media.Type,
 				filename:    filename,
-				originalUrl: media.Url,
+				originalUrl: mediaUrl,
+				width:       width,
+				height:      height,
+				altText:     media.AltText,
 			})
 		}
 	}
@@ -255,6 +610,14 @@ func baseName(url string) string {
 	return nameWithoutParams[0]
 }
 
+// tweetUrl returns the permalink of a tweet.
+func tweetUrl(screenName, id string) string {
+	if screenName == "" {
+		return fmt.Sprintf("https://twitter.com/i/web/status/%s", id)
+	}
+	return fmt.Sprintf("https://twitter.com/%s/status/%s", screenName, id)
+}
+
 func rubyDateToTime(timeString string) (time.Time, error) {
 	rubyDateFormat := "Mon Jan 02 15:04:05 -0700 2006"
 	timestamp, err := time.Parse(rubyDateFormat, timeString)
@@ -264,10 +627,11 @@ func rubyDateToTime(timeString string) (time.Time, error) {
 	return timestamp, nil
 }
 
-// Check if this is a reply (in_reply_to flag or starting by @ or .
-func isReply(tweet Tweet) bool {
+// Check if this is a reply to other users (in_reply_to flag or starting by @ or .
+// Replies to the author own tweets, in screenName account, are not considered replies.
+func isReply(tweet Tweet, screenName string) bool {
 	if tweet.ReplyToTweetId != "" {
-		return true
+		return !isSelfReply(tweet, screenName)
 	}
 	if strings.HasPrefix(tweet.FullText, "@") {
 		return true
@@ -280,219 +644,450 @@ func isReply(tweet Tweet) bool {
 	return false
 }
 
-func isTruncated(tweet Tweet) bool {
+//=============================================================================
+// Truncated tweets
+
+// SkippedTweet describes a tweet that could not be converted, in the skipped
+// items report.
+type SkippedTweet struct {
+	Id        string
+	Url       string
+	Reason    string
+	Text      string
+	CreatedAt time.Time
+}
+
+// retweetPrefix matches the beginning of old style retweets: "RT @user: "
+var retweetPrefix = regexp.MustCompile(`^RT @(\w+):`)
+
+func isRetweet(tweet Tweet) bool {
+	return retweetPrefix.MatchString(tweet.FullText)
+}
+
+// recoverTruncated restores what can be recovered from a tweet whose text has
+// been cut:
+//   - Links cut by the truncation are restored from the tweet entities.
+//   - Old style retweets, cut at 140 characters, get back the links from their
+//     entities that were lost in the process.
+//
+// Tweets simply ending with an ellipsis are kept as is. It returns the reason
+// why the content of the tweet is lost, or an empty string if the tweet can be
+// converted.
+func recoverTruncated(tweet *Tweet) string {
+	if tweet.RetweetedStatus != nil {
+		// The original tweet is available
+		return ""
+	}
+	retweet := isRetweet(*tweet)
+
 	if strings.HasSuffix(tweet.FullText, "…") {
-		return true
+		text := strings.TrimSuffix(tweet.FullText, "…")
+		start := strings.LastIndexAny(text, " \n") + 1
+		if word := text[start:]; strings.HasPrefix(word, "http") {
+			// A link has been cut
+			if u := findEntityUrl(*tweet, word); u != "" {
+				tweet.FullText = text[:start] + u
+			} else if retweet {
+				tweet.FullText = strings.TrimRight(text[:start], " \n") + "…"
+			} else {
+				return "truncated link"
+			}
+		}
 	}
-	return false
+
+	if retweet {
+		// Add links from entities that do not fit in the retweet anymore
+		for _, u := range tweet.Entities.Urls {
+			if !strings.Contains(tweet.FullText, u.Url) {
+				tweet.FullText += " " + u.Url
+			}
+		}
+		for _, m := range tweet.ExtendedEntities.Media {
+			if !strings.Contains(tweet.FullText, m.Url) {
+				tweet.FullText += " " + m.Url
+			}
+		}
+		return ""
+	}
+
+	if tweet.Truncated {
+		return "truncated text"
+	}
+	return ""
 }
 
-// TODO: Render links to mentioned people to Twitter accounts.
-// TODO: Replace other shortened URL buff.ly, tinyurl, etc, to remove dependency to third-party service.
-func tweetToMd(tweet Tweet, targetDir string, mediafiles []localMedia) string {
-	// Insert two spaces at end of line to generate Markdown line break
-	markdown := strings.Replace(tweet.FullText, "\n", "  \n", -1)
-	// Replace Twitter URLs with original URLs
+// findEntityUrl returns the full short URL from tweet entities starting with prefix.
+func findEntityUrl(tweet Tweet, prefix string) string {
 	for _, u := range tweet.Entities.Urls {
-		mdURL := renderLink(u.DisplayUrl, u.ExpandedUrl)
-		markdown = strings.Replace(markdown, u.Url, mdURL, 1)
-	}
-	// Replace Twitter URL for media with media rendering
-	for i, media := range mediafiles {
-		fullpath := filepath.Join("/", targetDir, media.filename)
-		mdMedia := ""
-		switch media.mediaType {
-		case "photo":
-			mdMedia = fmt.Sprintf("\n![attachment %2d](%s)\n", i, fullpath)
-		case "video":
-			template := `
-<video controls>
- <source src="%s" type="video/mp4">
- Your browser does not support the video tag.
-</video>
-`
-			mdMedia = fmt.Sprintf(template, fullpath)
-		case "animated_gif":
-			template := `
-<video controls loop>
- <source src="%s" type="video/mp4">
- Your browser does not support the video tag.
-</video>
-`
-			mdMedia = fmt.Sprintf(template, fullpath)
+		if strings.HasPrefix(u.Url, prefix) {
+			return u.Url
+		}
+	}
+	for _, m := range tweet.ExtendedEntities.Media {
+		if strings.HasPrefix(m.Url, prefix) {
+			return m.Url
+		}
+	}
+	return ""
+}
+
+// writeSkippedReport writes the list of tweets that could not be converted
+// in skipped.json file, at the root of outputDir. The report of a previous
+// conversion is removed when all tweets were converted.
+func writeSkippedReport(outputDir string, skipped []SkippedTweet) error {
+	reportFile := filepath.Join(outputDir, "skipped.json")
+	if len(skipped) == 0 {
+		if err := os.Remove(reportFile); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	report, err := json.MarshalIndent(skipped, "", "\t")
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%d tweet(s) could not be converted. See: %s\n", len(skipped), reportFile)
+	return ioutil.WriteFile(reportFile, report, 0644)
+}
+
+//=============================================================================
+// Link resolution
+//
+// Links to tweets and other embeddable content are embedded, using oEmbed,
+// and short URLs are replaced by their target.
+// Resolving them requires network requests: all the links of the converted
+// tweets are collected first and resolved concurrently, before rendering.
+
+// linkPool resolves links with a few workers, limiting the requests sent to
+// each host so that link shorteners do not throttle us.
+var linkPool = semweb.Pool{Workers: 8, HostConcurrency: 2, HostInterval: 250 * time.Millisecond}
+
+// shortenerHosts are the link shortening services whose links are resolved.
+var shortenerHosts = map[string]bool{
+	"buff.ly":              true,
+	"bit.ly":               true,
+	"t.co":                 true,
+	"tinyurl.com":          true,
+	"feedproxy.google.com": true,
+}
+
+// linkTarget is the result of the resolution of a link.
+type linkTarget struct {
+	// Url is the link target, with short URLs resolved.
+	Url string
+	// Title replaces the displayed URL when the link is rendered.
+	Title string
+	// Embed is the HTML rendering of the link, for embeddable content.
+	Embed string
+	// Video is the ID of the YouTube video the link points to, rendered with
+	// its thumbnail when available.
+	Video     string
+	Thumbnail []byte
+	// Card is the preview of the target page, when preview cards are enabled.
+	Card *linkCard
+}
+
+// needsResolution returns true if resolving link requires network requests.
+func (c twitterConverter) needsResolution(link string) bool {
+	u, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+	return shortenerHosts[u.Host] || youtubeVideoId(link) != "" || semweb.OEmbedUrl(link) != "" ||
+		((c.discoverEmbeds || c.previewCards) && isWebUrl(u))
+}
+
+// isCached returns true if the resolution of link is available from the
+// client cache.
+func (c twitterConverter) isCached(link string) bool {
+	cache := c.client.Cache
+	if u, err := url.Parse(link); err == nil && shortenerHosts[u.Host] {
+		entry, ok := cache.Get(link)
+		if !ok || entry.FinalUrl == "" {
+			return false
 		}
-		markdown = strings.Replace(markdown, media.originalUrl, mdMedia, 1)
+		// The target of the short URL can also require resolution
+		link = c.client.LinkTarget(entry)
+	}
+	if id := youtubeVideoId(link); id != "" {
+		_, ok := cache.Get(youtubeWatchUrl(id))
+		_, hasThumbnail := cache.GetFile(youtubeThumbnailUrl(id))
+		return ok && hasThumbnail
 	}
-	return markdown
+	if endpoint := semweb.OEmbedUrl(link); endpoint != "" {
+		_, ok := cache.Get(endpoint)
+		return ok
+	}
+	if c.discoverEmbeds || c.previewCards {
+		entry, ok := cache.Get(link)
+		if !ok || entry.FinalUrl == "" {
+			return false
+		}
+		if c.discoverEmbeds && entry.OEmbedUrl != "" {
+			if _, ok = cache.Get(entry.OEmbedUrl); !ok {
+				return false
+			}
+		}
+		if c.previewCards && entry.Image != "" {
+			if _, ok = cache.GetFile(entry.Image); !ok {
+				return false
+			}
+		}
+	}
+	return true
 }
 
-func renderLink(displayUrl, link string) string {
+// resolveLink returns the target of link. Resolutions are stored in the client
+// cache, if any.
+func (c twitterConverter) resolveLink(link string) linkTarget {
+	target := linkTarget{Url: link}
 	u, err := url.Parse(link)
 	if err != nil {
 		// Not a valid URL, just return the link as is:
-		return link
+		return target
+	}
+	if shortenerHosts[u.Host] {
+		target.Title, target.Url = resolveShortUrl(c.client, link)
+	}
+	if id := youtubeVideoId(target.Url); id != "" {
+		target.Video = id
+		var title string
+		if title, target.Thumbnail = resolveYouTubeVideo(c.client, id); title != "" {
+			target.Title = title
+		}
+	} else if target.Embed = c.embedLink(target.Url); target.Embed == "" && c.previewCards {
+		target.Card = previewCard(c.client, target.Url)
+	}
+	// Tracking parameters are removed from all links
+	target.Url = semweb.CleanUrl(target.Url)
+	return target
+}
+
+// collectLinks appends to links the links of tweet that are rendered by
+// tweetToMd, including the ones from embedded quoted tweets.
+func (c twitterConverter) collectLinks(tweet Tweet, embedQuotes bool, links []string) []string {
+	if tweet.RetweetedStatus != nil {
+		return c.collectLinks(*tweet.RetweetedStatus, false, links)
 	}
-	switch u.Host {
-	case "twitter.com":
-		// If expanded tweet start with https://www.twitter.com, try embedding the tweet:
-		return twitterEmbed(displayUrl, link)
-	case "buff.ly", "bit.ly", "t.co", "tinyurl.com", "feedproxy.google.com":
-		return resolveShortUrl(displayUrl, link)
-		// TODO: Youtube
-		//case "youtu.be", "youtube.com":
-		//	return defaultLink(displayUrl, link)
+	for _, u := range tweet.Entities.Urls {
+		if quoted, ok := c.quotedTweet(u.ExpandedUrl); ok && embedQuotes {
+			links = c.collectLinks(quoted, false, links)
+			continue
+		}
+		links = append(links, c.rewriter.Rewrite(u.ExpandedUrl))
+	}
+	return links
+}
+
+// resolveLinks resolves the links of the tweets of threads, concurrently, so
+// that they can be rendered without waiting for the network.
+func (c twitterConverter) resolveLinks(threads []Thread) {
+	var pending []string
+	for _, thread := range threads {
+		for _, tweet := range thread {
+			for _, link := range c.collectLinks(tweet, true, nil) {
+				if _, ok := c.resolved[link]; ok || !c.needsResolution(link) {
+					continue
+				}
+				if c.isCached(link) || c.client.Cache.IsOffline() {
+					// No network request needed
+					c.resolved[link] = c.resolveLink(link)
+					continue
+				}
+				pending = append(pending, link)
+			}
+		}
 	}
 
-	return defaultLink(displayUrl, link)
+	var mu sync.Mutex
+	linkPool.Run(pending, func(link string) {
+		fmt.Println("Processing link:", link)
+		target := c.resolveLink(link)
+		mu.Lock()
+		c.resolved[link] = target
+		mu.Unlock()
+	})
+}
+
+// renderLink renders a link from a tweet as Markdown, with the files it
+// requires attached to post. It also returns the link target, rewritten and
+// with short URLs resolved.
+func (c twitterConverter) renderLink(post *Post, displayUrl, link string) (string, string) {
+	if rewritten := c.rewriter.Rewrite(link); rewritten != link {
+		link = rewritten
+		displayUrl = strings.TrimPrefix(strings.TrimPrefix(link, "https://"), "http://")
+	}
+	target, ok := c.resolved[link]
+	if !ok {
+		target = c.resolveLink(link)
+	}
+	if target.Embed != "" {
+		// Embeds are HTML blocks, which must end with a blank line
+		return "\n\n" + target.Embed + "\n\n", target.Url
+	}
+	if target.Thumbnail != nil {
+		return videoToMd(post, target, displayUrl, c.youtubeFacade), target.Url
+	}
+	if target.Card != nil {
+		return cardToMd(post, *target.Card, target.Url), target.Url
+	}
+	if target.Title != "" {
+		displayUrl = target.Title
+	}
+	return defaultLink(displayUrl, target.Url), target.Url
 }
 
 //=============================================================================
 // Link rendering / embedding
 
-type OEmbed struct {
-	EmbedType    string `json:"type"`
-	URL          string
-	AuthorName   string `json:"author_name"`
-	AuthorURL    string `json:"author_url"`
-	HTML         string
-	Width        int
-	Height       int
-	CacheAge     string `json:"cache_age"`
-	ProviderName string `json:"provider_name"`
-	ProviderURL  string `json:"provider_url"`
-	Version      string
-}
-
-func twitterEmbed(displayUrl, link string) string {
-	fmt.Println("Processing link:", link)
-	apiEndpoint := fmt.Sprintf("https://publish.twitter.com/oembed?url=%s", link)
-	client := httpClient()
-	resp, err := client.Get(apiEndpoint)
-	if err != nil {
-		fmt.Println(err)
-		return defaultLink(displayUrl, link)
+// embedLink returns the HTML representation of link provided by its oEmbed
+// endpoint, sanitized, or an empty string if link cannot be embedded. Players
+// are removed, unless enabled.
+func (c twitterConverter) embedLink(link string) string {
+	markup := c.oembedHtml(link)
+	if !c.embedPlayers {
+		markup = strings.TrimSpace(sanitizeEmbed(markup, false))
 	}
-	defer resp.Body.Close()
+	return markup
+}
 
-	if resp.StatusCode == 200 {
-		data, err := ioutil.ReadAll(resp.Body)
+// oembedHtml returns the sanitized HTML representation of link provided by
+// its oEmbed endpoint, players included, from the client cache when possible.
+// The endpoint is found in the registry of known providers or, when discovery
+// is enabled, in the page link points to.
+func (c twitterConverter) oembedHtml(link string) string {
+	client := c.client
+	endpoint := semweb.OEmbedUrl(link)
+	if u, err := url.Parse(link); err == nil && endpoint == "" && c.discoverEmbeds && isWebUrl(u) {
+		entry, err := client.ResolveLink(link)
 		if err != nil {
-			fmt.Println(err)
-			return defaultLink(displayUrl, link)
+			if !errors.Is(err, semweb.ErrOffline) {
+				fmt.Println(err)
+			}
+			return ""
 		}
+		endpoint = entry.OEmbedUrl
+	}
+	if endpoint == "" {
+		return ""
+	}
+	if entry, ok := client.Cache.Get(endpoint); ok || client.Cache.IsOffline() {
+		return entry.OEmbed
+	}
 
-		var embed OEmbed
-		if err = json.Unmarshal(data, &embed); err != nil {
-			fmt.Println(err)
-			return defaultLink(displayUrl, link)
-		}
-		// Remove Javascript
-		policy := bluemonday.UGCPolicy()
-		policy.AllowStyling()
-		html := policy.Sanitize(embed.HTML)
+	res, err := client.Fetch(endpoint)
+	if err != nil {
+		fmt.Println(err)
+		return ""
+	}
+	if res.Status != 200 {
+		client.Cache.Put(semweb.CacheEntry{Url: endpoint, FinalUrl: res.FinalUrl, Status: res.Status})
+		return ""
+	}
+
+	var embed semweb.OEmbed
+	if err = json.Unmarshal(res.Body, &embed); err != nil {
+		fmt.Println(err)
+		return ""
+	}
+	markup := embed.HTML
+	if embed.EmbedType == "photo" && embed.URL != "" {
+		// The HTML of photos is optional, they are described by their URL
+		markup = photoEmbed(embed)
+	}
+	markup = sanitizeEmbed(markup, true)
+	if strings.TrimSpace(markup) == "" {
+		// Nothing to embed: the link is rendered another way
+		return ""
+	}
+	client.Cache.Put(semweb.CacheEntry{Url: endpoint, FinalUrl: res.FinalUrl, Status: res.Status, OEmbed: markup})
+	return markup
+}
+
+// photoEmbed returns the HTML rendering of an oEmbed photo.
+func photoEmbed(embed semweb.OEmbed) string {
+	return fmt.Sprintf(`<img src="%s" width="%d" height="%d" alt="%s">`,
+		html.EscapeString(embed.URL), embed.Width, embed.Height, html.EscapeString(embed.Title))
+}
 
-		return "\n" + html
+// sanitizeEmbed removes Javascript from the HTML of embedded content. With
+// players, players from https URLs, in iframes, are kept.
+func sanitizeEmbed(embed string, players bool) string {
+	policy := bluemonday.UGCPolicy()
+	policy.AllowStyling()
+	policy.AllowAttrs("alt").OnElements("img")
+	if players {
+		policy.AllowAttrs("width", "height", "frameborder", "allow", "allowfullscreen", "title").OnElements("iframe")
+		policy.AllowAttrs("src").Matching(httpsUrl).OnElements("iframe")
 	}
-	return defaultLink(displayUrl, link)
+	return policy.Sanitize(embed)
 }
 
-// TODO refactor: Reuse function from metadata package.
-func resolveShortUrl(displayUrl, link string) string {
-	fmt.Println("Processing link:", link)
-	client := httpClient()
-Loop:
-	// Try to resolve link 7 times, as sometimes you can find a chain of redirects before
-	// reaching the canonical link.
-	for redirect := 0; redirect <= 7; redirect++ {
-		resp, err := client.Get(link)
-		if err != nil {
+var httpsUrl = regexp.MustCompile(`^https://`)
+
+// isWebUrl returns true for http and https URLs.
+func isWebUrl(u *url.URL) bool {
+	return u.Scheme == "http" || u.Scheme == "https"
+}
+
+// resolveShortUrl follows the redirects from a short URL and returns the title
+// of the target page, or its host, with the target URL, without tracking
+// parameters. The title is empty if the short URL cannot be resolved.
+func resolveShortUrl(client semweb.Client, link string) (string, string) {
+	entry, err := client.ResolveLink(link)
+	if err != nil {
+		if !errors.Is(err, semweb.ErrOffline) {
 			fmt.Println(err)
-			return defaultLink(displayUrl, link)
-		}
-
-		switch resp.StatusCode {
-		case 301, 302:
-			location := resp.Header.Get("Location")
-			// Retry resolving the next link, with new discovered location
-			link, err := RedirectUrl(link, location)
-			if err != nil {
-				// Not a valid URL, just return the original link as is
-				_ = resp.Body.Close()
-				break Loop
-			}
-			// TODO: Display using debug or verbose option
-			// fmt.Println("=> Resolved as", link)
-
-			u, err := url.Parse(link)
-			if err != nil {
-				// Not a valid URL, just return the original link as is
-				_ = resp.Body.Close()
-				break Loop
-			}
-			// Retry resolving the next link, with new discovered value
-			displayUrl = u.Host
-			link = location
-		case 200:
-			page, err := semweb.ReadPage(resp.Body)
-			if err == nil {
-				displayUrl = page.Title()
-			}
-			resp.Body.Close()
-			break Loop
-		default:
-			fmt.Println("Ignored HTTP Status Code:", resp.StatusCode)
-			resp.Body.Close()
-			break Loop
 		}
+		return "", link
 	}
-
-	return defaultLink(displayUrl, link)
+	return linkTitle(entry), client.LinkTarget(entry)
 }
 
-func defaultLink(displayUrl, link string) string {
-	if len(displayUrl) > 50 {
-		displayUrl = displayUrl[:50] + "…"
+// linkTitle returns the title to display for a resolved link: the title of
+// the target page or, when it has none, its host.
+func linkTitle(entry semweb.CacheEntry) string {
+	if entry.Title != "" {
+		return entry.Title
+	}
+	if entry.FinalUrl != entry.Url {
+		if u, err := url.Parse(entry.FinalUrl); err == nil {
+			return u.Host
+		}
 	}
-	return fmt.Sprintf("[%s](%s)", displayUrl, link)
+	return ""
 }
 
-func httpClient() *http.Client {
-	transport := &http.Transport{
-		DialContext: (&net.Dialer{
-			Timeout: 5 * time.Second,
-		}).DialContext,
-		TLSHandshakeTimeout: 5 * time.Second,
-	}
-	return &http.Client{
-		Timeout:   time.Second * 15,
-		Transport: transport,
-		CheckRedirect: func(req *http.Request, via []*http.Request) error {
-			return http.ErrUseLastResponse
-		},
+// defaultLink renders a link as Markdown, with displayUrl, which can be the
+// title of the target page, as text.
+func defaultLink(displayUrl, link string) string {
+	// Truncate long URLs, without cutting UTF-8 characters
+	if runes := []rune(displayUrl); len(runes) > 50 {
+		displayUrl = string(runes[:50]) + "…"
 	}
+	return fmt.Sprintf("[%s](%s)", escapeMarkdown(displayUrl, false), link)
 }
 
 //=============================================================================
 // Helpers
 
-// copyFile the src file to dst. Any existing file will be overwritten and will not
-// copy file attributes.
-func copyFile(src, dst string) error {
-	in, err := os.Open(src)
-	if err != nil {
-		return err
-	}
-	defer in.Close()
+var clientLink = regexp.MustCompile(`<a href="([^"]*)"[^>]*>([^<]*)</a>`)
 
-	out, err := os.Create(dst)
-	if err != nil {
-		return err
+// parseClientLink returns the client application from the tweet source, an
+// HTML link to the application page.
+func parseClientLink(source string) Client {
+	matches := clientLink.FindStringSubmatch(source)
+	if matches == nil {
+		return Client{Name: html.UnescapeString(source)}
 	}
-	defer out.Close()
+	return Client{Name: html.UnescapeString(matches[2]), Url: html.UnescapeString(matches[1])}
+}
 
-	_, err = io.Copy(out, in)
-	if err != nil {
-		return err
+func appendUnique(list []string, value string) []string {
+	for _, v := range list {
+		if v == value {
+			return list
+		}
 	}
-	return out.Close()
+	return append(list, value)
 }
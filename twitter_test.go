@@ -0,0 +1,303 @@
+package dpk_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/processone/dpk"
+)
+
+func TestTwitterToMDLayouts(t *testing.T) {
+	tests := []struct {
+		archive string
+		posts   []string
+		media   string
+	}{
+		{
+			archive: "fixtures/twitter-2018",
+			posts:   []string{"2016/02/15/001", "2016/02/16/001", "2017/01/06/001", "2018/12/27/001", "2018/12/27/002"},
+			media:   "2018/12/27/002/1078253946104922113-DvYx1YlWsAAbCdE.jpg",
+		},
+		{
+			archive: "fixtures/twitter-2022",
+			posts:   []string{"2022/10/31/001", "2022/10/31/002", "2022/11/02/001"},
+			media:   "2022/10/31/002/1587200000000000000-FgXyZ12WAAEaBcD.jpg",
+		},
+	}
+
+	for _, tt := range tests {
+		outputDir := t.TempDir()
+		if err := dpk.TwitterToMD(tt.archive, outputDir, dpk.TwitterOptions{}); err != nil {
+			t.Errorf("Cannot convert archive '%s': %s", tt.archive, err)
+			continue
+		}
+
+		for _, post := range tt.posts {
+			postDir := filepath.Join(outputDir, filepath.FromSlash(post))
+			if _, err := os.Stat(filepath.Join(postDir, "post.md")); err != nil {
+				t.Errorf("Missing post.md for '%s' in '%s': %s", post, tt.archive, err)
+			}
+			data, err := ioutil.ReadFile(filepath.Join(postDir, "metadata.json"))
+			if err != nil {
+				t.Errorf("Missing metadata.json for '%s' in '%s': %s", post, tt.archive, err)
+				continue
+			}
+			var metadata dpk.Metadata
+			if err = json.Unmarshal(data, &metadata); err != nil {
+				t.Errorf("Cannot parse metadata.json for '%s' in '%s': %s", post, tt.archive, err)
+			}
+			if metadata.Lang != "en" {
+				t.Errorf("Incorrect lang for '%s' in '%s'. Got: '%s' Expected: 'en'", post, tt.archive, metadata.Lang)
+			}
+		}
+
+		if _, err := os.Stat(filepath.Join(outputDir, filepath.FromSlash(tt.media))); err != nil {
+			t.Errorf("Media file was not copied from '%s': %s", tt.archive, err)
+		}
+		post, err := ioutil.ReadFile(filepath.Join(outputDir, filepath.Dir(filepath.FromSlash(tt.media)), "post.md"))
+		if err != nil {
+			t.Errorf("Cannot read post with media from '%s': %s", tt.archive, err)
+			continue
+		}
+		if !strings.Contains(string(post), filepath.Base(tt.media)) || strings.Contains(string(post), "https://t.co/") {
+			t.Errorf("Media was not rendered in post from '%s': %s", tt.archive, post)
+		}
+	}
+}
+
+func TestTwitterToMDThreads(t *testing.T) {
+	archive := "fixtures/twitter-2022"
+	outputDir := t.TempDir()
+	if err := dpk.TwitterToMD(archive, outputDir, dpk.TwitterOptions{}); err != nil {
+		t.Errorf("Cannot convert archive '%s': %s", archive, err)
+		return
+	}
+
+	// Self-replies are merged in a single post, in thread order
+	post, err := ioutil.ReadFile(filepath.Join(outputDir, "2022", "11", "02", "001", "post.md"))
+	if err != nil {
+		t.Errorf("Cannot read thread post: %s", err)
+		return
+	}
+	expected := "Let's talk about XMPP scalability. A thread:\n\n---\n\n" +
+		"First, clustering is built in.\n\n---\n\n" +
+		"Second, you can shard users across nodes."
+	if string(post) != expected {
+		t.Errorf("Incorrect thread content. Got: '%s' Expected: '%s'", post, expected)
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, "2022", "11", "02", "002")); err == nil {
+		t.Errorf("Self-replies should not be converted as separate posts")
+	}
+
+	// Replies to other users are skipped by default
+	if _, err := os.Stat(filepath.Join(outputDir, "2022", "11", "03")); err == nil {
+		t.Errorf("Replies to other users should be skipped by default")
+	}
+
+	outputDir = t.TempDir()
+	if err := dpk.TwitterToMD(archive, outputDir, dpk.TwitterOptions{Replies: dpk.KeepReplies}); err != nil {
+		t.Errorf("Cannot convert archive '%s': %s", archive, err)
+		return
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, "2022", "11", "03", "001", "post.md")); err != nil {
+		t.Errorf("Replies to other users should be kept with KeepReplies policy: %s", err)
+	}
+}
+
+func TestTwitterToMDThreadsWithoutAccount(t *testing.T) {
+	// Without account data, self-replies are the replies to tweets of the
+	// archive
+	archive := fstest.MapFS{
+		"tweet.js": &fstest.MapFile{Data: []byte(`window.YTD.tweet.part0 = [ {
+  "id_str" : "1",
+  "created_at" : "Mon Jan 07 10:00:00 +0000 2019",
+  "full_text" : "A thread:",
+  "lang" : "en"
+}, {
+  "id_str" : "2",
+  "in_reply_to_status_id_str" : "1",
+  "in_reply_to_screen_name" : "processone",
+  "created_at" : "Mon Jan 07 10:01:00 +0000 2019",
+  "full_text" : "Second tweet.",
+  "lang" : "en"
+}, {
+  "id_str" : "3",
+  "in_reply_to_status_id_str" : "100",
+  "in_reply_to_screen_name" : "alice",
+  "created_at" : "Mon Jan 07 10:02:00 +0000 2019",
+  "full_text" : "@alice Thanks!",
+  "lang" : "en"
+} ]`)},
+	}
+	outputDir := t.TempDir()
+	if err := dpk.TwitterArchiveToMD(archive, outputDir, dpk.TwitterOptions{}); err != nil {
+		t.Errorf("Cannot convert archive: %s", err)
+		return
+	}
+	post, err := ioutil.ReadFile(filepath.Join(outputDir, "2019", "01", "07", "001", "post.md"))
+	if err != nil {
+		t.Errorf("Cannot read thread post: %s", err)
+		return
+	}
+	if expected := "A thread:\n\n---\n\nSecond tweet."; string(post) != expected {
+		t.Errorf("Incorrect thread content. Got: '%s' Expected: '%s'", post, expected)
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, "2019", "01", "07", "002")); err == nil {
+		t.Errorf("Replies to other users should be skipped by default")
+	}
+}
+
+func TestTwitterToMDSeparateReplies(t *testing.T) {
+	archive := "fixtures/twitter-2022"
+	outputDir := t.TempDir()
+	if err := dpk.TwitterToMD(archive, outputDir, dpk.TwitterOptions{Replies: dpk.SeparateReplies}); err != nil {
+		t.Errorf("Cannot convert archive '%s': %s", archive, err)
+		return
+	}
+
+	replyDir := filepath.Join(outputDir, "replies", "2022", "11", "03", "001")
+	data, err := ioutil.ReadFile(filepath.Join(replyDir, "metadata.json"))
+	if err != nil {
+		t.Errorf("Reply was not converted in replies directory: %s", err)
+		return
+	}
+	var metadata dpk.Metadata
+	if err = json.Unmarshal(data, &metadata); err != nil {
+		t.Errorf("Cannot parse reply metadata: %s", err)
+		return
+	}
+	expected := "https://twitter.com/mickael/status/1588000000000000000"
+	if metadata.Type != "reply" || metadata.InReplyTo != expected {
+		t.Errorf("Incorrect reply metadata. Got: '%s' '%s' Expected: 'reply' '%s'", metadata.Type, metadata.InReplyTo, expected)
+	}
+	post, err := ioutil.ReadFile(filepath.Join(replyDir, "post.md"))
+	if err != nil {
+		t.Errorf("Cannot read reply post: %s", err)
+		return
+	}
+	if !strings.Contains(string(post), "[@mickael]("+expected+")") {
+		t.Errorf("Reply does not link to original tweet: %s", post)
+	}
+
+	// Regular posts are not impacted
+	if _, err := os.Stat(filepath.Join(outputDir, "2022", "11", "03")); err == nil {
+		t.Errorf("Replies should not be converted along regular posts with SeparateReplies policy")
+	}
+}
+
+func TestTwitterToMDTruncated(t *testing.T) {
+	archive := "fixtures/twitter-2018"
+	outputDir := t.TempDir()
+	if err := dpk.TwitterToMD(archive, outputDir, dpk.TwitterOptions{}); err != nil {
+		t.Errorf("Cannot convert archive '%s': %s", archive, err)
+		return
+	}
+
+	// Links lost in old style retweets are restored from entities
+	post, err := ioutil.ReadFile(filepath.Join(outputDir, "2016", "02", "15", "001", "post.md"))
+	if err != nil {
+		t.Errorf("Retweet was not converted: %s", err)
+		return
+	}
+	expected := "Read our new post: [process-one.net/en/ejabberd/](https://www.process-one.net/en/ejabberd/)"
+	if !strings.HasSuffix(string(post), expected) {
+		t.Errorf("Retweet link was not restored. Got: '%s' Expected suffix: '%s'", post, expected)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(outputDir, "2016", "02", "15", "001", "metadata.json"))
+	if err != nil {
+		t.Errorf("Cannot read retweet metadata: %s", err)
+		return
+	}
+	var metadata dpk.Metadata
+	if err = json.Unmarshal(data, &metadata); err != nil || metadata.Type != "repost" {
+		t.Errorf("Retweet should have the repost type: %s", data)
+	}
+
+	// Tweets ending with an ellipsis are kept as is
+	post, err = ioutil.ReadFile(filepath.Join(outputDir, "2016", "02", "16", "001", "post.md"))
+	if err != nil {
+		t.Errorf("Tweet ending with an ellipsis was not converted: %s", err)
+	} else if string(post) != "Well, that was unexpected…" {
+		t.Errorf("Incorrect content for tweet ending with ellipsis: %s", post)
+	}
+
+	// Truncated tweets that cannot be recovered are reported
+	data, err = ioutil.ReadFile(filepath.Join(outputDir, "skipped.json"))
+	if err != nil {
+		t.Errorf("Missing skipped items report: %s", err)
+		return
+	}
+	var skipped []dpk.SkippedTweet
+	if err = json.Unmarshal(data, &skipped); err != nil {
+		t.Errorf("Cannot parse skipped items report: %s", err)
+		return
+	}
+	if len(skipped) != 1 || skipped[0].Id != "699907000000000000" {
+		t.Errorf("Incorrect skipped items report: %s", data)
+	}
+
+	// The report is removed when a later conversion skips no tweet
+	if err = dpk.TwitterToMD("fixtures/twitter-2022", outputDir, dpk.TwitterOptions{}); err != nil {
+		t.Errorf("Cannot convert archive: %s", err)
+		return
+	}
+	if _, err = os.Stat(filepath.Join(outputDir, "skipped.json")); !os.IsNotExist(err) {
+		t.Errorf("Stale skipped items report was not removed: %v", err)
+	}
+}
+
+func TestTwitterToMDQuotes(t *testing.T) {
+	archive := "fixtures/twitter-2022"
+	outputDir := t.TempDir()
+	if err := dpk.TwitterToMD(archive, outputDir, dpk.TwitterOptions{}); err != nil {
+		t.Errorf("Cannot convert archive '%s': %s", archive, err)
+		return
+	}
+
+	// Quoted tweets from the archive are rendered locally, with their media
+	postDir := filepath.Join(outputDir, "2022", "11", "04", "001")
+	post, err := ioutil.ReadFile(filepath.Join(postDir, "post.md"))
+	if err != nil {
+		t.Errorf("Cannot read quote post: %s", err)
+		return
+	}
+	for _, expected := range []string{
+		"Still our most popular release \n\n> Paris, by the Seine.",
+		"> — [@processone, 31 Oct 2022](https://twitter.com/processone/status/1587200000000000000)",
+	} {
+		if !strings.Contains(string(post), expected) {
+			t.Errorf("Quoted tweet was not rendered locally. Got: '%s' Expected: '%s'", post, expected)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(postDir, "1587200000000000000-FgXyZ12WAAEaBcD.jpg")); err != nil {
+		t.Errorf("Quoted tweet media was not copied: %s", err)
+	}
+
+	tests := []struct {
+		postDir  string
+		postType string
+	}{
+		{postDir: filepath.Join(outputDir, "2022", "10", "31", "001"), postType: "microblog"},
+		{postDir: postDir, postType: "quote"},
+	}
+	for _, tt := range tests {
+		data, err := ioutil.ReadFile(filepath.Join(tt.postDir, "metadata.json"))
+		if err != nil {
+			t.Errorf("Cannot read metadata: %s", err)
+			continue
+		}
+		var metadata dpk.Metadata
+		if err = json.Unmarshal(data, &metadata); err != nil {
+			t.Errorf("Cannot parse metadata: %s", err)
+			continue
+		}
+		if metadata.Type != tt.postType {
+			t.Errorf("Incorrect post type for '%s'. Got: '%s' Expected: '%s'", tt.postDir, metadata.Type, tt.postType)
+		}
+	}
+}
@@ -1,25 +0,0 @@
-package dpk
-
-import (
-	"net/url"
-)
-
-// RedirectUrl returns a valid full URL from an original URL and a "Location" Header.
-// It support local redirection on same host.
-func RedirectUrl(originalUrl, locationHeader string) (string, error) {
-	newUrl, err := url.Parse(locationHeader)
-	if err != nil {
-		return "", err
-	}
-
-	// This is a relative URL, we need to use the host from original URL
-	if newUrl.Host == "" && newUrl.Scheme == "" {
-		oldUrl, err := url.Parse(originalUrl)
-		if err != nil {
-			return "", err
-		}
-		newUrl.Host = oldUrl.Host
-		newUrl.Scheme = oldUrl.Scheme
-	}
-	return newUrl.String(), nil
-}
@@ -0,0 +1,131 @@
+package dpk
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/processone/dpk/pkg/semweb"
+)
+
+//=============================================================================
+// YouTube links
+//
+// Links to YouTube videos are rendered as the video thumbnail, stored beside
+// the post, linking to the video, so that reading the post does not send any
+// request to YouTube. Optionally, clicking the thumbnail replaces it with the
+// video player from youtube-nocookie.com: YouTube is only contacted when the
+// reader decides to play the video.
+
+// youtubeIdPattern matches valid YouTube video IDs.
+var youtubeIdPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{11}$`)
+
+// youtubePathPrefixes are the paths of YouTube URLs followed by the video ID.
+var youtubePathPrefixes = []string{"/embed/", "/shorts/", "/live/", "/v/"}
+
+// Size of the "hqdefault" thumbnail, available for all videos
+const (
+	youtubeThumbnailWidth  = 480
+	youtubeThumbnailHeight = 360
+)
+
+// youtubeVideoId returns the ID of the YouTube video link points to, or an
+// empty string if link is not a link to a YouTube video.
+func youtubeVideoId(link string) string {
+	u, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+	var id string
+	switch strings.TrimPrefix(strings.ToLower(u.Host), "www.") {
+	case "youtu.be":
+		id = strings.TrimPrefix(u.Path, "/")
+	case "youtube.com", "m.youtube.com", "youtube-nocookie.com":
+		if u.Path == "/watch" {
+			id = u.Query().Get("v")
+			break
+		}
+		for _, prefix := range youtubePathPrefixes {
+			if strings.HasPrefix(u.Path, prefix) {
+				id = strings.TrimPrefix(u.Path, prefix)
+			}
+		}
+	}
+	if !youtubeIdPattern.MatchString(id) {
+		return ""
+	}
+	return id
+}
+
+func youtubeWatchUrl(id string) string {
+	return "https://www.youtube.com/watch?v=" + id
+}
+
+func youtubeThumbnailUrl(id string) string {
+	return "https://i.ytimg.com/vi/" + id + "/hqdefault.jpg"
+}
+
+// resolveYouTubeVideo returns the title of the video and its thumbnail, from
+// the client cache when possible. The thumbnail is nil if it is not
+// available.
+func resolveYouTubeVideo(client semweb.Client, id string) (string, []byte) {
+	var title string
+	if entry, err := client.ResolveLink(youtubeWatchUrl(id)); err == nil {
+		title = entry.Title
+	}
+
+	thumbnailUrl := youtubeThumbnailUrl(id)
+	if data, ok := client.Cache.GetFile(thumbnailUrl); ok {
+		return title, data
+	}
+	res, err := client.Fetch(thumbnailUrl)
+	if err != nil || res.Status != 200 {
+		return title, nil
+	}
+	if err = client.Cache.PutFile(thumbnailUrl, res.Body); err != nil {
+		fmt.Println("Cannot cache video thumbnail:", err)
+	}
+	return title, res.Body
+}
+
+// videoToMd renders a link to a YouTube video as its thumbnail, attached to
+// post, linking to the video. With facade, the thumbnail is replaced by the
+// video player when clicked.
+func videoToMd(post *Post, target linkTarget, displayUrl string, facade bool) string {
+	id := target.Video
+	filename := "youtube-" + id + ".jpg"
+	title := target.Title
+	if title == "" {
+		title = displayUrl
+	}
+	if !hasAttachment(*post, filename) {
+		thumbnail := target.Thumbnail
+		post.Attachments = append(post.Attachments, Attachment{
+			Filename:    filename,
+			Type:        "thumbnail",
+			OriginalUrl: youtubeThumbnailUrl(id),
+			Width:       youtubeThumbnailWidth,
+			Height:      youtubeThumbnailHeight,
+			AltText:     title,
+			Open: func() (io.ReadCloser, error) {
+				return ioutil.NopCloser(bytes.NewReader(thumbnail)), nil
+			},
+		})
+	}
+
+	if !facade {
+		return fmt.Sprintf("\n[![%s](%s)](%s)\n", escapeMarkdown(title, false), filename, youtubeWatchUrl(id))
+	}
+	player := fmt.Sprintf(`<iframe width="%d" height="%d" src="https://www.youtube-nocookie.com/embed/%s?autoplay=1" `+
+		`allow="autoplay; encrypted-media; picture-in-picture" allowfullscreen></iframe>`,
+		youtubeThumbnailWidth, youtubeThumbnailHeight, id)
+	return fmt.Sprintf("\n<a class=\"youtube-facade\" href=\"%s\" onclick=\"this.outerHTML='%s'; return false;\">"+
+		"<img src=\"%s\" alt=\"%s\" width=\"%d\" height=\"%d\"></a>\n",
+		youtubeWatchUrl(id), html.EscapeString(player), filename, html.EscapeString(title),
+		youtubeThumbnailWidth, youtubeThumbnailHeight)
+}